@@ -0,0 +1,88 @@
+package shell
+
+import "time"
+
+// Alerts notify the caller that a command has crossed a threshold,
+// without otherwise affecting it. Each callback is made from its own
+// goroutine, or from the goroutine copying the command's output.
+
+// StderrLinesAlert is an Option to call fn, once, when the command
+// has written more than n lines to stderr
+func StderrLinesAlert(n int, fn func(*Result)) Option {
+	return func(s *command) {
+		count := 0
+		s.onStderr = append(s.onStderr, func(string) {
+			count++
+			if count == n+1 {
+				fn(s.Result)
+			}
+		})
+	}
+}
+
+// SilenceAlert is an Option to call fn whenever the command has
+// written nothing to either stdout or stderr for the duration d.
+// It is called again only after the command has produced more output.
+func SilenceAlert(d time.Duration, fn func(*Result)) Option {
+	return func(s *command) {
+		if d <= 0 {
+			return
+		}
+
+		activity := make(chan struct{}, 1)
+		w := activityWriter(activity)
+		s.stdout = append(s.stdout, w)
+		s.stderr = append(s.stderr, w)
+		s.monitors = append(s.monitors, func() { s.watchSilence(d, activity, fn) })
+	}
+}
+
+// RuntimeAlert is an Option to call fn, once, if the
+// command is still running after the duration d
+func RuntimeAlert(d time.Duration, fn func(*Result)) Option {
+	return func(s *command) {
+		if d <= 0 {
+			return
+		}
+
+		s.monitors = append(s.monitors, func() {
+			select {
+			case <-s.done:
+			case <-s.clock.After(d):
+				fn(s.Result)
+			}
+		})
+	}
+}
+
+// watchSilence calls fn each time a period d passes without activity
+func (sc *command) watchSilence(d time.Duration, activity <-chan struct{}, fn func(*Result)) {
+	for {
+		select {
+		case <-sc.done:
+			return
+		case <-activity:
+			continue
+		case <-sc.clock.After(d):
+			fn(sc.Result)
+		}
+
+		// stay quiet until the command is heard from again
+		select {
+		case <-sc.done:
+			return
+		case <-activity:
+		}
+	}
+}
+
+// activityWriter signals on its channel whenever it is written to
+type activityWriter chan struct{}
+
+func (w activityWriter) Write(p []byte) (int, error) {
+	select {
+	case w <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
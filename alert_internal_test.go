@@ -0,0 +1,59 @@
+package shell
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStderrLinesAlert(t *testing.T) {
+	var alerts int32
+	alert := StderrLinesAlert(2, func(*Result) { atomic.AddInt32(&alerts, 1) })
+
+	Run("echo a >&2; echo b >&2", alert)
+	if n := atomic.LoadInt32(&alerts); n != 0 {
+		t.Errorf("Expected no alert at the threshold, got %d", n)
+	}
+
+	Run("for i in 1 2 3 4 5; do echo $i >&2; done", alert)
+	if n := atomic.LoadInt32(&alerts); n != 1 {
+		t.Errorf("Expected a single alert beyond the threshold, got %d", n)
+	}
+}
+
+func TestSilenceAlert(t *testing.T) {
+	var alerts int32
+	result := Run("echo start; sleep 0.3; echo middle; sleep 0.3; echo end", SilenceAlert(150*time.Millisecond, func(*Result) {
+		atomic.AddInt32(&alerts, 1)
+	}))
+	<-result.Ready()
+
+	if n := atomic.LoadInt32(&alerts); n != 2 {
+		t.Errorf("Expected an alert for each silent period, got %d", n)
+	}
+
+	if result.FullStdout().Text() != "start\nmiddle\nend" {
+		t.Errorf("Alerts should not affect the output, got %q", result.FullStdout().Text())
+	}
+}
+
+func TestRuntimeAlert(t *testing.T) {
+	var alerts int32
+	alert := RuntimeAlert(100*time.Millisecond, func(r *Result) {
+		if r.IsReady() {
+			t.Error("Expected the command to still be running")
+		}
+		atomic.AddInt32(&alerts, 1)
+	})
+
+	result := Run("sleep 0.3", alert)
+	if result.TimedOut() || result.ExitCode() != 0 {
+		t.Error("Alerts should not stop the command")
+	}
+
+	Run("true", alert)
+	time.Sleep(150 * time.Millisecond)
+	if n := atomic.LoadInt32(&alerts); n != 1 {
+		t.Errorf("Expected a single alert, got %d", n)
+	}
+}
@@ -0,0 +1,146 @@
+package shell
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-cmd/cmd"
+)
+
+// Process is a running, or runnable, command as seen by this package.
+// The go-cmd *cmd.Cmd used by default satisfies it.
+type Process interface {
+	// Start launches the process, returning a channel
+	// that receives the final status once it is done
+	Start() <-chan cmd.Status
+
+	// Stop terminates the process
+	Stop() error
+
+	// Status returns the current status of the process
+	Status() cmd.Status
+
+	// Done returns a channel that is closed once the process is done
+	Done() <-chan struct{}
+}
+
+// Backend creates the Process for the given executable and arguments
+type Backend func(name string, args []string) Process
+
+// Killer is implemented by a Process that can be terminated forcibly.
+// Kill is called once the KillGrace period of a command stopped for
+// timing out or being canceled has elapsed, or straight away with
+// the ProcessGroup Option, as a real process would be sent SIGKILL.
+type Killer interface {
+	Kill() error
+}
+
+// ClockUser is implemented by a Process that keeps time, such as the
+// fake processes of the shelltest package. It is given the command's
+// Clock, as set by WithClock, before it is started.
+type ClockUser interface {
+	UseClock(c Clock)
+}
+
+// replayInterval is how often a Backend's Process
+// is polled for output lines not yet replayed
+const replayInterval = 10 * time.Millisecond
+
+// backendProcess creates the command's Process with its Backend.
+// A Backend only reports output as lines in its status, so these
+// are replayed through the command's writers, which would otherwise
+// be given the output of a real process.
+func (sc *command) backendProcess(name string, args []string) Process {
+	p := sc.backend(name, args)
+	if cu, ok := p.(ClockUser); ok {
+		cu.UseClock(sc.clock)
+	}
+	if len(sc.stdout) == 0 && len(sc.stderr) == 0 {
+		return p
+	}
+
+	stdout := multiWriter(nil, sc.stdout...)
+	stderr := multiWriter(nil, sc.stderr...)
+	if e := sc.encoding; e != nil {
+		stdout = e.wrap(stdout)
+		stderr = e.wrap(stderr)
+	}
+	return &replayProcess{
+		Process: p,
+		stdout:  stdout,
+		stderr:  stderr,
+		done:    make(chan struct{}),
+	}
+}
+
+// replayProcess is a Process whose output lines are written
+// to stdout and stderr as they appear in its status. It is
+// done only once all of its output has been written.
+type replayProcess struct {
+	Process
+	stdout io.Writer
+	stderr io.Writer
+	once   sync.Once
+	done   chan struct{}
+}
+
+func (p *replayProcess) Start() <-chan cmd.Status {
+	statusChan := p.Process.Start()
+	p.once.Do(func() { go p.replay() })
+	return statusChan
+}
+
+func (p *replayProcess) Done() <-chan struct{} {
+	return p.done
+}
+
+func (p *replayProcess) Kill() error {
+	return killProcess(p.Process)
+}
+
+// killProcess terminates the Process forcibly if it can be,
+// or else only asks it to stop
+func killProcess(p Process) error {
+	if k, ok := p.(Killer); ok {
+		return k.Kill()
+	}
+	return p.Stop()
+}
+
+// replay polls the process for new output lines until it is done
+func (p *replayProcess) replay() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(replayInterval)
+	defer ticker.Stop()
+
+	var stdout, stderr int
+	for {
+		var done bool
+		select {
+		case <-p.Process.Done():
+			done = true
+		case <-ticker.C:
+		}
+
+		st := p.Process.Status()
+		stdout = replayLines(p.stdout, st.Stdout, stdout)
+		stderr = replayLines(p.stderr, st.Stderr, stderr)
+		if done {
+			return
+		}
+	}
+}
+
+// replayLines writes the lines from index seen onwards to w,
+// returning the number of lines now written
+func replayLines(w io.Writer, lines []string, seen int) int {
+	if w == nil || seen >= len(lines) {
+		return seen
+	}
+	for _, line := range lines[seen:] {
+		io.WriteString(w, line+"\n")
+	}
+	return len(lines)
+}
@@ -0,0 +1,37 @@
+package shell
+
+import "context"
+
+// RunAll executes the commands one after the other, each configured
+// with the Options, and returns the Results of those that were run,
+// in order. All the commands are run, regardless of failures.
+// No more commands are launched once the context is done.
+func RunAll(ctx context.Context, commands []string, options ...Option) []*Result {
+	return runAll(ctx, commands, false, options)
+}
+
+// RunAllStopOnFailure is as RunAll, but runs no more commands
+// once one fails, by erroring, timing out or exiting non-zero
+func RunAllStopOnFailure(ctx context.Context, commands []string, options ...Option) []*Result {
+	return runAll(ctx, commands, true, options)
+}
+
+func runAll(ctx context.Context, commands []string, stopOnFailure bool, options []Option) []*Result {
+	options = append([]Option{Context(ctx)}, options...)
+
+	var results []*Result
+	for _, command := range commands {
+		if ctx.Err() != nil {
+			break
+		}
+
+		r := Run(command, options...)
+		<-r.Ready()
+		results = append(results, r)
+
+		if stopOnFailure && failed(r) {
+			break
+		}
+	}
+	return results
+}
@@ -0,0 +1,49 @@
+package shell
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunAll(t *testing.T) {
+	commands := []string{"echo one", "exit 3", "echo three"}
+
+	results := RunAll(context.Background(), commands)
+	if len(results) != 3 {
+		t.Fatalf("Expected all commands to run, got %d Results", len(results))
+	}
+	if results[1].ExitCode() != 3 || results[2].Stdout().Text() != "three" {
+		t.Errorf("Unexpected Results: %v", results)
+	}
+
+	results = RunAllStopOnFailure(context.Background(), commands)
+	if len(results) != 2 {
+		t.Fatalf("Expected to stop at the failing command, got %d Results", len(results))
+	}
+	if results[0].Stdout().Text() != "one" || results[1].ExitCode() != 3 {
+		t.Errorf("Unexpected Results: %v", results)
+	}
+}
+
+func TestRunAllContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	results := RunAll(ctx, []string{"sleep 5", "echo never"})
+	if len(results) != 1 {
+		t.Fatalf("Expected no command to run once the context is done, got %d Results", len(results))
+	}
+	if !results[0].TimedOut() {
+		t.Errorf("Expected the context to time out the command: %v", results[0])
+	}
+}
+
+func TestRunAllOptionPrecedence(t *testing.T) {
+	ctx := NewContext(context.Background(), Env([]string{"WHO=context"}))
+
+	results := RunAll(ctx, []string{"echo $WHO"}, Env([]string{"WHO=explicit"}))
+	if got := results[0].Stdout().Text(); got != "explicit" {
+		t.Errorf("Expected explicit Options to win over the context's, got %q", got)
+	}
+}
@@ -0,0 +1,36 @@
+package shell
+
+import "time"
+
+// CgroupLimits are the resource limits of the transient cgroup
+// created by the Cgroup Option. Zero values impose no limit.
+type CgroupLimits struct {
+	MemoryMax int64   // memory.max, in bytes
+	CPUMax    float64 // cpu.max, as a number of CPUs, e.g. 0.5
+	PidsMax   int     // pids.max
+
+	// Parent is the cgroup directory in which to create the transient
+	// cgroup, and must be set. It must be delegated to the current user,
+	// have the needed controllers available and hold no processes of
+	// its own, as for a cgroup created with systemd-run -p Delegate=yes
+	// and left empty.
+	Parent string
+}
+
+// CgroupUsage is the resource usage measured by the cgroup of a
+// command run with the Cgroup Option. Peaks are zero if the kernel
+// does not report them.
+type CgroupUsage struct {
+	MemoryPeak int64 // bytes
+	PidsPeak   int
+	CPUTime    time.Duration
+}
+
+// CgroupUsage returns the resources used by a command run with the
+// Cgroup Option, or nil if there was no cgroup or it is not yet done
+func (r *Result) CgroupUsage() *CgroupUsage {
+	if !r.IsReady() {
+		return nil
+	}
+	return r.cgroupUsage
+}
@@ -0,0 +1,42 @@
+//go:build linux
+// +build linux
+
+package shell
+
+import (
+	"os"
+	"testing"
+)
+
+// cgroupTestParent names the environment variable giving the delegated,
+// empty cgroup the tests create their cgroups in
+const cgroupTestParent = "SHELL_TEST_CGROUP"
+
+func TestCgroupOption(t *testing.T) {
+	if res := Run("true", Cgroup(CgroupLimits{PidsMax: 10})); !res.IsError() {
+		t.Error("Expected an error without a Parent cgroup")
+	}
+
+	parent := os.Getenv(cgroupTestParent)
+	if parent == "" {
+		t.Skipf("%s does not name a delegated cgroup", cgroupTestParent)
+	}
+
+	res := Run("cat /proc/self/cgroup; cat /sys/fs/cgroup$(sed -n 's/^0:://p' /proc/self/cgroup)/pids.max",
+		Cgroup(CgroupLimits{PidsMax: 10, MemoryMax: 64 << 20, Parent: parent}))
+	if res.IsError() {
+		t.Fatalf("Unable to create a cgroup in %s: %v", parent, res.Err())
+	}
+
+	lines := res.FullStdout().Lines()
+	if len(lines) == 0 || lines[len(lines)-1] != "10" {
+		t.Errorf("Expected the command to run in the limited cgroup: %v %v", lines, res.FullStderr().Lines())
+	}
+
+	if usage := res.CgroupUsage(); usage == nil {
+		t.Error("Expected the cgroup usage to be measured")
+	}
+	if Run("true").CgroupUsage() != nil {
+		t.Error("Expected no usage without a cgroup")
+	}
+}
@@ -0,0 +1,160 @@
+package shell
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// cgroupPeriod is the cpu.max period over which the quota applies
+const cgroupPeriod = 100000 // microseconds
+
+// cgroupSeq makes the names of the transient cgroups unique
+var cgroupSeq int64
+
+// Cgroup is an Option to confine the command in a transient cgroup v2
+// with the given limits, removed once the command is done. Usage
+// measured by the cgroup is then available via Result.CgroupUsage.
+// The limits must name a delegated Parent cgroup to create it in.
+func Cgroup(limits CgroupLimits) Option {
+	return func(s *command) {
+		parent := limits.Parent
+		if err := checkCgroupParent(parent); err != nil {
+			s.err = fmt.Errorf("Cgroup: %v", err)
+			return
+		}
+
+		name := fmt.Sprintf("shell-%d-%d", os.Getpid(), atomic.AddInt64(&cgroupSeq, 1))
+		dir := filepath.Join(parent, name)
+
+		s.setups = append(s.setups, func() error {
+			if err := createCgroup(parent, dir, limits); err != nil {
+				return fmt.Errorf("Cgroup: %v", err)
+			}
+
+			s.cleanups = append(s.cleanups, func() {
+				s.Result.cgroupUsage = cgroupUsage(dir)
+				removeCgroup(dir)
+			})
+			return nil
+		})
+
+		// the launcher moves itself into the cgroup before running the command
+		s.prefix = append(s.prefix, "/bin/sh", "-c", `echo $$ > "$0" && exec "$@"`, filepath.Join(dir, "cgroup.procs"))
+	}
+}
+
+// checkCgroupParent returns an error unless parent is a cgroup v2
+// directory that holds no processes of its own. Controllers can only
+// be enabled for the children of such a cgroup, so the cgroup of the
+// current process, with the current process in it, cannot be used.
+func checkCgroupParent(parent string) error {
+	if parent == "" {
+		return errors.New("no Parent cgroup given")
+	}
+	if _, err := os.Stat(filepath.Join(parent, "cgroup.controllers")); err != nil {
+		return fmt.Errorf("%s is not a cgroup v2 directory", parent)
+	}
+
+	procs, err := ioutil.ReadFile(filepath.Join(parent, "cgroup.procs"))
+	if err != nil {
+		return err
+	}
+	if len(strings.TrimSpace(string(procs))) > 0 {
+		return fmt.Errorf("%s has processes of its own, so it cannot have child cgroups with controllers", parent)
+	}
+	return nil
+}
+
+// createCgroup creates the cgroup and applies the limits to it
+func createCgroup(parent, dir string, limits CgroupLimits) error {
+	settings := map[string]string{}
+	var controllers []string
+	if limits.MemoryMax > 0 {
+		controllers = append(controllers, "+memory")
+		settings["memory.max"] = strconv.FormatInt(limits.MemoryMax, 10)
+	}
+	if limits.CPUMax > 0 {
+		controllers = append(controllers, "+cpu")
+		quota := int64(limits.CPUMax * cgroupPeriod)
+		settings["cpu.max"] = fmt.Sprintf("%d %d", quota, cgroupPeriod)
+	}
+	if limits.PidsMax > 0 {
+		controllers = append(controllers, "+pids")
+		settings["pids.max"] = strconv.Itoa(limits.PidsMax)
+	}
+
+	if len(controllers) > 0 {
+		control := filepath.Join(parent, "cgroup.subtree_control")
+		if err := ioutil.WriteFile(control, []byte(strings.Join(controllers, " ")), 0644); err != nil {
+			return fmt.Errorf("unable to enable controllers in %s: %v", parent, err)
+		}
+	}
+
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return err
+	}
+
+	for file, value := range settings {
+		if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(value), 0644); err != nil {
+			removeCgroup(dir)
+			return fmt.Errorf("unable to set %s: %v", file, err)
+		}
+	}
+	return nil
+}
+
+// removeCgroup removes the cgroup, waiting briefly
+// for its last processes to be reaped
+func removeCgroup(dir string) {
+	for i := 0; i < 50; i++ {
+		err := syscall.Rmdir(dir)
+		if err != syscall.EBUSY {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// cgroupUsage reads the usage accounted by the cgroup
+func cgroupUsage(dir string) *CgroupUsage {
+	usage := &CgroupUsage{
+		MemoryPeak: readCgroupInt(filepath.Join(dir, "memory.peak")),
+		PidsPeak:   int(readCgroupInt(filepath.Join(dir, "pids.peak"))),
+	}
+
+	f, err := os.Open(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return usage
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "usage_usec" {
+			usec, _ := strconv.ParseInt(fields[1], 10, 64)
+			usage.CPUTime = time.Duration(usec) * time.Microsecond
+		}
+	}
+	return usage
+}
+
+// readCgroupInt reads a file holding a single integer, returning 0 if
+// it is missing or holds no number
+func readCgroupInt(path string) int64 {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	return n
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package shell
+
+import "fmt"
+
+// Cgroup is an Option to confine the command in a transient cgroup
+// with the given limits. It is only supported on Linux.
+func Cgroup(limits CgroupLimits) Option {
+	return func(s *command) {
+		s.err = fmt.Errorf("Cgroup: %w", ErrUnsupported)
+	}
+}
@@ -0,0 +1,112 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// CIStyle selects the log markers written by the CIMarkers Option
+type CIStyle int
+
+const (
+	// GitHubActions uses ::group:: and ::error:: workflow commands
+	GitHubActions CIStyle = iota
+
+	// TeamCity uses ##teamcity service messages
+	TeamCity
+)
+
+// CIMarkers is an Option to copy the command's output, as it
+// arrives, to w wrapped in a collapsible group for the given CI
+// system. If the command fails, the group is followed by an error
+// annotation giving the command and how it failed.
+func CIMarkers(w io.Writer, style CIStyle) Option {
+	return func(s *command) {
+		s.ci = &ciDecorator{w: w, style: style}
+	}
+}
+
+// ------------------------------------------------------------------
+
+// ciDecorator writes a command's output between CI grouping markers
+type ciDecorator struct {
+	mu    sync.Mutex
+	w     io.Writer
+	style CIStyle
+}
+
+// open starts the group for the command
+func (d *ciDecorator) open(command string) {
+	switch d.style {
+	case TeamCity:
+		d.printf("##teamcity[blockOpened name='%s']\n", teamCityEscape(command))
+	default:
+		d.printf("::group::%s\n", firstLine(command))
+	}
+}
+
+// close ends the group, annotating it if the command failed
+func (d *ciDecorator) close(command string, r *Result) {
+	failed := r.IsError() || r.TimedOut() || r.Canceled() || r.ExitCode() != 0
+
+	switch d.style {
+	case TeamCity:
+		d.printf("##teamcity[blockClosed name='%s']\n", teamCityEscape(command))
+		if failed {
+			d.printf("##teamcity[buildProblem description='%s']\n", teamCityEscape(r.String()))
+		}
+	default:
+		d.printf("::endgroup::\n")
+		if failed {
+			d.printf("::error::%s\n", githubEscape(r.String()))
+		}
+	}
+}
+
+// writer returns a lineWriter copying lines into the group
+func (d *ciDecorator) writer() *lineWriter {
+	return newLineWriter(func(line string) {
+		d.printf("%s\n", line)
+	})
+}
+
+func (d *ciDecorator) printf(format string, args ...interface{}) {
+	d.mu.Lock()
+	fmt.Fprintf(d.w, format, args...)
+	d.mu.Unlock()
+}
+
+// ------------------------------------------------------------------
+
+// firstLine returns s up to its first newline, for use as a title
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i] + " ..."
+	}
+	return s
+}
+
+var (
+	githubEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+
+	teamCityEscaper = strings.NewReplacer(
+		"|", "||",
+		"'", "|'",
+		"[", "|[",
+		"]", "|]",
+		"\n", "|n",
+		"\r", "|r",
+	)
+)
+
+// githubEscape escapes a workflow command message
+func githubEscape(s string) string {
+	return githubEscaper.Replace(s)
+}
+
+// teamCityEscape escapes a service message attribute value
+func teamCityEscape(s string) string {
+	return teamCityEscaper.Replace(s)
+}
@@ -0,0 +1,58 @@
+package shell
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCIMarkers(t *testing.T) {
+	tests := []struct {
+		name   string
+		style  CIStyle
+		cmd    string
+		expect []string
+	}{
+		{
+			"github success",
+			GitHubActions,
+			"echo hello",
+			[]string{"::group::echo hello", "hello", "::endgroup::"},
+		},
+		{
+			"github failure",
+			GitHubActions,
+			"echo oops >&2; exit 2",
+			[]string{"::group::echo oops >&2; exit 2", "oops", "::endgroup::", "::error::\"echo oops >&2; exit 2\": exited with code 2"},
+		},
+		{
+			"teamcity failure",
+			TeamCity,
+			"echo '[x]'; exit 1",
+			[]string{
+				"##teamcity[blockOpened name='echo |'|[x|]|'; exit 1']",
+				"[x]",
+				"##teamcity[blockClosed name='echo |'|[x|]|'; exit 1']",
+				"##teamcity[buildProblem description=",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			res := Run(tt.cmd, CIMarkers(&buf, tt.style))
+			<-res.Ready()
+
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			if len(lines) != len(tt.expect) {
+				t.Fatalf("Expected %d lines, got:\n%s", len(tt.expect), buf.String())
+			}
+			for i, want := range tt.expect {
+				if !strings.HasPrefix(lines[i], want) {
+					t.Errorf("Line %d: expected prefix %q, got %q", i, want, lines[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package shell
+
+import "time"
+
+// Clock is the source of time used by a command
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// After returns a channel that receives the time
+	// once the duration has elapsed
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the Clock backed by the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,203 @@
+// Command shellrun runs a shell command through the shell package
+// and reports its Result, giving scripts the same semantics as
+// programmatic users of the package.
+//
+// Usage:
+//
+//	shellrun [flags] command
+//	shellrun [flags] -f script
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/brinick/shell"
+)
+
+// envFlags collects repeated -env flags
+type envFlags []string
+
+func (e *envFlags) String() string {
+	return strings.Join(*e, ",")
+}
+
+func (e *envFlags) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("expected KEY=VALUE, got %q", value)
+	}
+	*e = append(*e, value)
+	return nil
+}
+
+// report is the JSON form of a shell.Result
+type report struct {
+	Command  string   `json:"command"`
+	Attempts int      `json:"attempts"`
+	ExitCode int      `json:"exit_code"`
+	PID      int      `json:"pid"`
+	Duration float64  `json:"duration"`
+	TimedOut bool     `json:"timed_out"`
+	Canceled bool     `json:"canceled"`
+	Crashed  bool     `json:"crashed"`
+	Error    string   `json:"error,omitempty"`
+	Stdout   []string `json:"stdout"`
+	Stderr   []string `json:"stderr"`
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes shellrun with the given arguments, writing to stdout
+// and stderr, and returns the exit code it should exit with
+func run(args []string, stdout, stderr io.Writer) int {
+	var env envFlags
+	flags := flag.NewFlagSet("shellrun", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	timeout := flags.Duration("timeout", 0, "kill the command after this long (0 = never)")
+	retries := flags.Int("retries", 0, "number of times to retry a failing command")
+	backoff := flags.Duration("retry-delay", time.Second, "pause between retries")
+	script := flags.String("f", "", "read the command from this file")
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	flags.Var(&env, "env", "add KEY=VALUE to the command's environment (repeatable)")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	command := strings.Join(flags.Args(), " ")
+	if *script != "" {
+		data, err := ioutil.ReadFile(*script)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		command = string(data)
+	}
+
+	if strings.TrimSpace(command) == "" {
+		flags.Usage()
+		return 2
+	}
+
+	// interrupting shellrun cancels the command
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+			close(stop)
+		case <-stop:
+		}
+	}()
+	defer func() {
+		select {
+		case <-stop:
+		default:
+			close(stop)
+		}
+	}()
+
+	options := []shell.Option{shell.Timeout(*timeout), shell.Cancel(stop)}
+	if len(env) > 0 {
+		options = append(options, shell.Env(env))
+	}
+
+	var (
+		res      *shell.Result
+		attempts int
+	)
+	for attempts = 1; ; attempts++ {
+		res = shell.Run(command, options...)
+		if succeeded(res) || attempts > *retries || res.Canceled() {
+			break
+		}
+		time.Sleep(*backoff)
+	}
+
+	if *asJSON {
+		return printJSON(stdout, stderr, command, attempts, res)
+	}
+	return printPlain(stdout, stderr, res)
+}
+
+// succeeded indicates if the command ran to completion with a zero exit code
+func succeeded(res *shell.Result) bool {
+	return !res.IsError() && !res.TimedOut() && !res.Canceled() && res.ExitCode() == 0
+}
+
+// printPlain writes the command's output to our own streams
+// and returns the exit code shellrun should exit with
+func printPlain(stdout, stderr io.Writer, res *shell.Result) int {
+	if out := res.Stdout(); !out.Empty() {
+		fmt.Fprintln(stdout, out.Text())
+	}
+	if out := res.Stderr(); !out.Empty() {
+		fmt.Fprintln(stderr, out.Text())
+	}
+
+	if !succeeded(res) && res.ExitCode() <= 0 {
+		fmt.Fprintln(stderr, "shellrun:", res)
+	}
+	return exitStatus(res)
+}
+
+// printJSON writes the result as JSON and returns
+// the exit code shellrun should exit with
+func printJSON(stdout, stderr io.Writer, command string, attempts int, res *shell.Result) int {
+	r := report{
+		Command:  command,
+		Attempts: attempts,
+		ExitCode: res.ExitCode(),
+		PID:      res.PID(),
+		Duration: res.Duration(),
+		TimedOut: res.TimedOut(),
+		Canceled: res.Canceled(),
+		Crashed:  res.Crashed(),
+		Stdout:   lines(res.Stdout()),
+		Stderr:   lines(res.Stderr()),
+	}
+	if err := res.Err(); err != nil {
+		r.Error = err.Error()
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	return exitStatus(res)
+}
+
+// lines returns the lines of out, as an empty rather
+// than nil slice if there are none, to encode as []
+func lines(out *shell.Output) []string {
+	if l := out.Lines(); l != nil {
+		return l
+	}
+	return []string{}
+}
+
+// exitStatus is the command's exit code if it exited by itself,
+// or 1 if it failed some other way
+func exitStatus(res *shell.Result) int {
+	if !succeeded(res) && res.ExitCode() <= 0 {
+		return 1
+	}
+	return res.ExitCode()
+}
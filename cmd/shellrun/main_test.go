@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunFlags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shellrun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "script.sh")
+	if err := ioutil.WriteFile(script, []byte("echo from script"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{"no command", nil, 2, "", "Usage"},
+		{"blank command", []string{" "}, 2, "", "Usage"},
+		{"unknown flag", []string{"-nope", "true"}, 2, "", "not defined"},
+		{"bad env", []string{"-env", "NOEQUALS", "true"}, 2, "", "KEY=VALUE"},
+		{"help", []string{"-h"}, 0, "", "Usage"},
+		{"args joined", []string{"echo", "a", "b"}, 0, "a b\n", ""},
+		{"env", []string{"-env", "FOO=bar", "echo $FOO"}, 0, "bar\n", ""},
+		{"script", []string{"-f", script}, 0, "from script\n", ""},
+		{"missing script", []string{"-f", filepath.Join(dir, "missing")}, 2, "", "no such file"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(test.args, &stdout, &stderr)
+			if code != test.code {
+				t.Errorf("exit code %d, want %d (stderr %q)", code, test.code, stderr.String())
+			}
+			if stdout.String() != test.stdout {
+				t.Errorf("stdout %q, want %q", stdout.String(), test.stdout)
+			}
+			if !strings.Contains(stderr.String(), test.stderr) {
+				t.Errorf("stderr %q, want it to contain %q", stderr.String(), test.stderr)
+			}
+		})
+	}
+}
+
+func TestRunExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"success", []string{"true"}, 0},
+		{"exit code", []string{"exit 3"}, 3},
+		{"exit code after retries", []string{"-retries", "2", "-retry-delay", "0", "exit 4"}, 4},
+		{"timeout", []string{"-timeout", "100ms", "sleep 5"}, 1},
+		{"json exit code", []string{"-json", "exit 5"}, 5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(test.args, &stdout, &stderr); code != test.code {
+				t.Errorf("exit code %d, want %d (stderr %q)", code, test.code, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		attempts int
+		stdout   []string
+		stderr   []string
+		timedOut bool
+	}{
+		{"no output", []string{"-json", "true"}, 0, 1, []string{}, []string{}, false},
+		{"output", []string{"-json", "echo out; echo err >&2"}, 0, 1, []string{"out"}, []string{"err"}, false},
+		{"retried", []string{"-json", "-retries", "1", "-retry-delay", "0", "exit 1"}, 1, 2, []string{}, []string{}, false},
+		{"timed out", []string{"-json", "-timeout", "100ms", "sleep 5"}, 1, 1, []string{}, []string{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(test.args, &stdout, &stderr); code != test.code {
+				t.Errorf("exit code %d, want %d (stderr %q)", code, test.code, stderr.String())
+			}
+
+			// the line arrays are always arrays, never null
+			var raw map[string]json.RawMessage
+			if err := json.Unmarshal(stdout.Bytes(), &raw); err != nil {
+				t.Fatalf("invalid JSON %q: %v", stdout.String(), err)
+			}
+			for _, key := range []string{"command", "attempts", "exit_code", "pid", "duration", "timed_out", "canceled", "crashed", "stdout", "stderr"} {
+				if _, ok := raw[key]; !ok {
+					t.Errorf("missing key %q in %s", key, stdout.String())
+				}
+			}
+			for _, key := range []string{"stdout", "stderr"} {
+				if v := string(raw[key]); !strings.HasPrefix(v, "[") {
+					t.Errorf("%s is %s, want an array", key, v)
+				}
+			}
+
+			var r report
+			if err := json.Unmarshal(stdout.Bytes(), &r); err != nil {
+				t.Fatal(err)
+			}
+			if r.Attempts != test.attempts {
+				t.Errorf("attempts %d, want %d", r.Attempts, test.attempts)
+			}
+			if r.TimedOut != test.timedOut {
+				t.Errorf("timed_out %v, want %v", r.TimedOut, test.timedOut)
+			}
+			if !reflect.DeepEqual(r.Stdout, test.stdout) {
+				t.Errorf("stdout %q, want %q", r.Stdout, test.stdout)
+			}
+			if !reflect.DeepEqual(r.Stderr, test.stderr) {
+				t.Errorf("stderr %q, want %q", r.Stderr, test.stderr)
+			}
+		})
+	}
+}
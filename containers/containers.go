@@ -0,0 +1,147 @@
+// Package containers wraps common docker and podman invocations
+// in typed functions, built on the shell package.
+package containers
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/brinick/shell"
+)
+
+// Engine is the container CLI to invoke, either a name to look up
+// in PATH or a path. If empty, docker is used if installed, else podman.
+var Engine string
+
+// ErrNoEngine is returned if no container CLI could be found
+var ErrNoEngine = errors.New("neither docker nor podman found in PATH")
+
+// Exit codes with which "run" reports its own failures,
+// as opposed to those of the command in the container
+const (
+	exitEngineError   = 125
+	exitCannotInvoke  = 126
+	exitNotFoundError = 127
+)
+
+// Mount is a directory of the host made available in a container
+type Mount struct {
+	Source   string // path on the host
+	Target   string // path in the container
+	ReadOnly bool
+}
+
+// String returns the mount in the form taken by the -v flag
+func (m Mount) String() string {
+	if m.ReadOnly {
+		return m.Source + ":" + m.Target + ":ro"
+	}
+	return m.Source + ":" + m.Target
+}
+
+// Error describes an invocation of the container CLI that failed
+type Error struct {
+	Args     []string
+	ExitCode int
+	Stderr   string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: exit code %d: %s", strings.Join(e.Args, " "), e.ExitCode, e.Stderr)
+}
+
+// Build builds the image from the context directory dir, tags it, and
+// returns its ID. A Dockerfile is expected at the root of dir.
+func Build(dir, tag string, options ...shell.Option) (string, error) {
+	if strings.HasPrefix(dir, "-") {
+		return "", fmt.Errorf("build context %q would be taken for an option", dir)
+	}
+
+	args := []string{"build", "--quiet"}
+	if tag != "" {
+		args = append(args, "--tag", tag)
+	}
+
+	args = append(args, dir)
+	res, err := run(args, options)
+	if err != nil {
+		return "", err
+	}
+
+	if res.ExitCode() != 0 {
+		return "", newError(res, args)
+	}
+
+	// with --quiet, the ID is the only output
+	lines := res.FullStdout().Lines()
+	if len(lines) == 0 {
+		return "", fmt.Errorf("build of %s printed no image ID", dir)
+	}
+	return strings.TrimSpace(lines[len(lines)-1]), nil
+}
+
+// Run runs args in a new container from the image, removed once it
+// exits, with the given mounts. The command's output and exit code
+// are those of the Result. The error is non-nil only if the container
+// could not be run, not if the command in it failed.
+func Run(image string, args []string, mounts []Mount, options ...shell.Option) (*shell.Result, error) {
+	if strings.HasPrefix(image, "-") {
+		return nil, fmt.Errorf("image %q would be taken for an option", image)
+	}
+
+	cliArgs := []string{"run", "--rm"}
+	for _, m := range mounts {
+		cliArgs = append(cliArgs, "--volume", m.String())
+	}
+	cliArgs = append(append(cliArgs, image), args...)
+
+	res, err := run(cliArgs, options)
+	if err != nil {
+		return res, err
+	}
+
+	switch res.ExitCode() {
+	case exitEngineError, exitCannotInvoke, exitNotFoundError:
+		return res, newError(res, cliArgs)
+	}
+	return res, nil
+}
+
+// run invokes the container CLI with the given arguments
+func run(args []string, options []shell.Option) (*shell.Result, error) {
+	engine, err := engine()
+	if err != nil {
+		return nil, err
+	}
+
+	res := shell.RunArgs(engine, args, options...)
+	if res.IsError() {
+		return res, res.Err()
+	}
+	return res, nil
+}
+
+// engine returns the container CLI to invoke
+func engine() (string, error) {
+	if Engine != "" {
+		return Engine, nil
+	}
+
+	for _, name := range []string{"docker", "podman"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return name, nil
+		}
+	}
+	return "", ErrNoEngine
+}
+
+// newError creates the Error for a failed invocation
+func newError(res *shell.Result, args []string) *Error {
+	return &Error{
+		Args:     args,
+		ExitCode: res.ExitCode(),
+		Stderr:   res.FullStderr().Text(),
+	}
+}
@@ -0,0 +1,110 @@
+package containers_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/brinick/shell/containers"
+)
+
+// fakeEngine installs a script standing in for the container CLI
+func fakeEngine(t *testing.T, script string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake engine is a shell script")
+	}
+
+	dir, err := ioutil.TempDir("", "containers")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "docker")
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	containers.Engine = path
+	t.Cleanup(func() {
+		containers.Engine = ""
+		os.RemoveAll(dir)
+	})
+}
+
+func TestBuild(t *testing.T) {
+	fakeEngine(t, `echo "step output" >&2; echo "sha256:abc123"; echo "$@" > "$(dirname "$0")/args"`)
+
+	id, err := containers.Build("ctx", "app:latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "sha256:abc123" {
+		t.Errorf("Unexpected image ID %q", id)
+	}
+
+	args, _ := ioutil.ReadFile(filepath.Join(filepath.Dir(containers.Engine), "args"))
+	if got := string(args); got != "build --quiet --tag app:latest ctx\n" {
+		t.Errorf("Unexpected arguments %q", got)
+	}
+}
+
+func TestBuildFailure(t *testing.T) {
+	fakeEngine(t, `echo "no Dockerfile" >&2; exit 1`)
+
+	_, err := containers.Build("ctx", "")
+	var cerr *containers.Error
+	if !errors.As(err, &cerr) {
+		t.Fatalf("Expected an Error, got %v", err)
+	}
+	if cerr.ExitCode != 1 || cerr.Stderr != "no Dockerfile" {
+		t.Errorf("Unexpected error %+v", cerr)
+	}
+}
+
+func TestRun(t *testing.T) {
+	fakeEngine(t, `echo "$@"; exit 3`)
+
+	mounts := []containers.Mount{
+		{Source: "/data", Target: "/in", ReadOnly: true},
+		{Source: "/tmp/out", Target: "/out"},
+	}
+	res, err := containers.Run("alpine", []string{"ls", "/in"}, mounts)
+	if err != nil {
+		t.Fatalf("A failing command should not be an error: %v", err)
+	}
+
+	if res.ExitCode() != 3 {
+		t.Errorf("Expected the command's exit code, got %d", res.ExitCode())
+	}
+	want := "run --rm --volume /data:/in:ro --volume /tmp/out:/out alpine ls /in"
+	if got := res.Stdout().Text(); got != want {
+		t.Errorf("Unexpected arguments %q", got)
+	}
+}
+
+func TestRunEngineFailure(t *testing.T) {
+	fakeEngine(t, `echo "Unable to find image" >&2; exit 125`)
+
+	_, err := containers.Run("missing", nil, nil)
+	var cerr *containers.Error
+	if !errors.As(err, &cerr) || cerr.ExitCode != 125 {
+		t.Errorf("Expected an engine Error, got %v", err)
+	}
+}
+
+func TestOptionLikeArguments(t *testing.T) {
+	fakeEngine(t, `touch "$(dirname "$0")/invoked"`)
+
+	if _, err := containers.Build("--file=/etc/passwd", ""); err == nil {
+		t.Error("Expected a build context starting with - to be refused")
+	}
+	if _, err := containers.Run("--privileged", []string{"sh"}, nil); err == nil {
+		t.Error("Expected an image starting with - to be refused")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(containers.Engine), "invoked")); !os.IsNotExist(err) {
+		t.Errorf("Expected the engine not to be invoked, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package shell
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// optionsKey is the context key under which Options are stored
+type optionsKey struct{}
+
+// NewContext returns a copy of ctx carrying the given Options,
+// in addition to any already carried by ctx. Commands run with
+// the Context Option for the returned context, or one derived
+// from it, have these Options applied, letting a caller configure
+// the commands run deeper in the call stack.
+func NewContext(ctx context.Context, options ...Option) context.Context {
+	inherited := optionsFromContext(ctx)
+	all := make([]Option, 0, len(inherited)+len(options))
+	all = append(append(all, inherited...), options...)
+	return context.WithValue(ctx, optionsKey{}, all)
+}
+
+// optionsFromContext returns the Options carried by ctx, if any
+func optionsFromContext(ctx context.Context) []Option {
+	if ctx == nil {
+		return nil
+	}
+	options, _ := ctx.Value(optionsKey{}).([]Option)
+	return options
+}
+
+// alsoContext is an Option to have the command also stopped once ctx
+// is done, in addition to the context it was given. Unlike Context,
+// it does not replace the command's context, so it can be applied by
+// the package after the caller's Options without overriding them.
+func alsoContext(ctx context.Context) Option {
+	return func(s *command) {
+		if s.ctx != ctx {
+			s.ctx = mergeContexts(s.ctx, ctx)
+		}
+	}
+}
+
+// mergedContext is done once either of its two contexts is,
+// and carries the values of the first
+type mergedContext struct {
+	context.Context
+	other context.Context
+
+	done chan struct{}
+	mu   sync.Mutex
+	err  error
+}
+
+// mergeContexts returns a context done once either a or b is
+func mergeContexts(a, b context.Context) context.Context {
+	m := &mergedContext{Context: a, other: b, done: make(chan struct{})}
+	go func() {
+		var err error
+		select {
+		case <-a.Done():
+			err = a.Err()
+		case <-b.Done():
+			err = b.Err()
+		}
+		m.mu.Lock()
+		m.err = err
+		m.mu.Unlock()
+		close(m.done)
+	}()
+	return m
+}
+
+func (m *mergedContext) Deadline() (time.Time, bool) {
+	deadline, ok := m.Context.Deadline()
+	if other, set := m.other.Deadline(); set && (!ok || other.Before(deadline)) {
+		return other, true
+	}
+	return deadline, ok
+}
+
+func (m *mergedContext) Done() <-chan struct{} {
+	return m.done
+}
+
+func (m *mergedContext) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
@@ -0,0 +1,25 @@
+package shell
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewContext(t *testing.T) {
+	ctx := NewContext(context.Background(), Env([]string{"LAYER=outer"}), Tag("request"))
+	ctx = NewContext(ctx, Env([]string{"INNER=yes"}))
+
+	res := Run(`echo "$LAYER $INNER"`, Context(ctx), Tag("call"))
+	if got := res.Stdout().Text(); got != "outer yes" {
+		t.Errorf("Expected context options to apply, got %q", got)
+	}
+
+	if tags := res.Tags(); len(tags) != 2 || tags[0] != "request" || tags[1] != "call" {
+		t.Errorf("Expected context tags before call tags, got %v", tags)
+	}
+
+	res = Run(`echo "$LAYER"`, Context(context.Background()))
+	if got := res.Stdout().Text(); got != "" {
+		t.Errorf("Expected a plain context to carry no options, got %q", got)
+	}
+}
@@ -0,0 +1,104 @@
+package shell
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// stats holds the package-wide command counters, published
+// through expvar under the name "shell"
+var stats = expvar.NewMap("shell")
+
+// running tracks the commands currently executing
+var running = struct {
+	sync.Mutex
+	results map[*Result]struct{}
+}{results: map[*Result]struct{}{}}
+
+func init() {
+	stats.Set("running", expvar.Func(func() interface{} {
+		running.Lock()
+		defer running.Unlock()
+		return len(running.results)
+	}))
+}
+
+// trackStart records that the command has been launched
+func trackStart(r *Result) {
+	stats.Add("started", 1)
+
+	running.Lock()
+	running.results[r] = struct{}{}
+	running.Unlock()
+}
+
+// trackDone records that the command is done, and how it ended
+func trackDone(r *Result) {
+	running.Lock()
+	delete(running.results, r)
+	running.Unlock()
+
+	stats.Add(exitClass(r), 1)
+}
+
+// exitClass is the counter under which a finished command is totalled
+func exitClass(r *Result) string {
+	switch {
+	case r.Crashed():
+		return "crashed"
+	case r.TimedOut():
+		return "timed_out"
+	case r.Canceled():
+		return "canceled"
+	case r.IsError() && !isExitError(r.Err()):
+		return "errored"
+	case r.ExitCode() != 0:
+		return "failed"
+	default:
+		return "succeeded"
+	}
+}
+
+// ------------------------------------------------------------------
+
+// runningCommand describes a running command for the debug handler
+type runningCommand struct {
+	Command string   `json:"command"`
+	PID     int      `json:"pid"`
+	Runtime float64  `json:"runtime"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+// DebugHandler returns an http.Handler reporting, as JSON, the
+// commands currently running along with the package's counters.
+// It is typically mounted at /debug/shell. The same counters are
+// also published through expvar, as "shell".
+func DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		running.Lock()
+		cmds := make([]runningCommand, 0, len(running.results))
+		for r := range running.results {
+			cmds = append(cmds, runningCommand{
+				Command: r.command,
+				PID:     r.PID(),
+				Runtime: r.Duration(),
+				Tags:    r.tags,
+			})
+		}
+		running.Unlock()
+
+		// longest running first
+		sort.Slice(cmds, func(i, j int) bool {
+			return cmds[i].Runtime > cmds[j].Runtime
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Running []runningCommand `json:"running"`
+			Totals  json.RawMessage  `json:"totals"`
+		}{cmds, json.RawMessage(stats.String())})
+	})
+}
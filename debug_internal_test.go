@@ -0,0 +1,46 @@
+package shell
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDebugHandler(t *testing.T) {
+	stop := make(chan struct{})
+	res := Run("sleep 5", Bkgd(), Cancel(stop), Tag("debug-test"))
+	defer func() {
+		close(stop)
+		<-res.Ready()
+	}()
+
+	for res.PID() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	rec := httptest.NewRecorder()
+	DebugHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/shell", nil))
+
+	var body struct {
+		Running []runningCommand   `json:"running"`
+		Totals  map[string]float64 `json:"totals"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, rec.Body.String())
+	}
+
+	found := false
+	for _, c := range body.Running {
+		if c.Command == "sleep 5" && c.PID == res.PID() && len(c.Tags) == 1 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the running command to be listed, got %+v", body.Running)
+	}
+
+	if body.Totals["running"] < 1 || body.Totals["started"] < 1 {
+		t.Errorf("Unexpected totals: %v", body.Totals)
+	}
+}
@@ -0,0 +1,12 @@
+package shell
+
+// Detached is a handle on a command launched, with Detach,
+// to outlive the current program
+type Detached struct {
+	PID int
+
+	// files to which the command's output is written,
+	// empty if the stream is discarded
+	Stdout string
+	Stderr string
+}
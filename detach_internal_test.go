@@ -0,0 +1,83 @@
+package shell
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetach(t *testing.T) {
+	dir, err := ioutil.TempDir("", "detach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "out.log")
+	d, err := Detach("echo $$; ps -o sid= -p $$; sleep 0.1; echo done", out, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+		data, _ := ioutil.ReadFile(out)
+		lines = strings.Fields(string(data))
+		if len(lines) == 3 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if len(lines) != 3 || lines[2] != "done" {
+		t.Fatalf("Unexpected output from detached command: %q", lines)
+	}
+
+	if lines[0] != lines[1] {
+		t.Errorf("Expected the command to lead its own session, pid %s is in session %s", lines[0], lines[1])
+	}
+
+	if lines[0] != strconv.Itoa(d.PID) {
+		t.Errorf("Expected handle PID %d to be the command's, got %s", d.PID, lines[0])
+	}
+}
+
+func TestDaemon(t *testing.T) {
+	dir, err := ioutil.TempDir("", "daemon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "out.log")
+	d, err := Daemon("sleep 0.2; echo $$; ps -o ppid= -p $$", out, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+		data, _ := ioutil.ReadFile(out)
+		lines = strings.Fields(string(data))
+		if len(lines) == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if len(lines) != 2 {
+		t.Fatalf("Unexpected output from daemon: %q", lines)
+	}
+
+	if lines[0] != strconv.Itoa(d.PID) {
+		t.Errorf("Expected reported PID %d to be the daemon's, got %s", d.PID, lines[0])
+	}
+
+	if lines[1] == strconv.Itoa(os.Getpid()) {
+		t.Error("Expected the daemon not to be a child of the current process")
+	}
+}
@@ -0,0 +1,139 @@
+//go:build !windows
+// +build !windows
+
+package shell
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Detach launches the command so that it keeps running after the
+// current program exits: it is placed in a new session, ignores SIGHUP,
+// and reads stdin from /dev/null. Its stdout and stderr are appended to the
+// given files, or discarded for empty paths. Unlike with Bkgd, no output
+// is captured and Detach returns as soon as the command is launched.
+func Detach(command string, stdout, stderr string, options ...Option) (*Detached, error) {
+	outFile, err := openDetachedOutput(stdout)
+	if err != nil {
+		return nil, err
+	}
+	if outFile != nil {
+		defer outFile.Close()
+	}
+
+	errFile, err := openDetachedOutput(stderr)
+	if err != nil {
+		return nil, err
+	}
+	if errFile != nil {
+		defer errFile.Close()
+	}
+
+	// an ignored signal stays ignored across exec, and so
+	// is inherited by everything the shell goes on to run
+	script := "trap '' HUP\n" + command
+
+	options = append(options, Bkgd(), detached(outFile, errFile))
+
+	res := Run(script, options...)
+	for res.PID() == 0 && !res.IsReady() {
+		time.Sleep(time.Millisecond)
+	}
+
+	if res.PID() == 0 {
+		return nil, res.Err()
+	}
+
+	return &Detached{PID: res.PID(), Stdout: stdout, Stderr: stderr}, nil
+}
+
+// daemonLauncher is run in a new session, and starts the daemon
+// as its own child before exiting, reporting the daemon's PID
+const daemonLauncher = `trap '' HUP
+bash -c "$1" </dev/null >>"$2" 2>>"$3" &
+echo $!`
+
+// Daemon launches the command as a daemon using the classic double
+// fork: an intermediate shell is started in a new session, launches
+// the command and exits at once, reporting the command's PID back
+// through its stdout. The daemon is thus reparented to init and never
+// becomes a zombie of the current program. Output is appended to
+// the given files, or discarded for empty paths.
+func Daemon(command string, stdout, stderr string, options ...Option) (*Detached, error) {
+	outPath, errPath := stdout, stderr
+	if outPath == "" {
+		outPath = os.DevNull
+	}
+	if errPath == "" {
+		errPath = os.DevNull
+	}
+
+	options = append(options, newSession())
+	res := RunWithArgs(daemonLauncher, []string{command, outPath, errPath}, options...)
+	<-res.Ready()
+
+	if res.IsError() {
+		return nil, res.Err()
+	}
+
+	out := strings.TrimSpace(res.Stdout().Text())
+	pid, err := strconv.Atoi(out)
+	if err != nil || res.ExitCode() != 0 {
+		return nil, fmt.Errorf(
+			"unable to launch daemon (exit code %d): %s %s",
+			res.ExitCode(),
+			out,
+			res.Stderr().Text(),
+		)
+	}
+
+	return &Detached{PID: pid, Stdout: stdout, Stderr: stderr}, nil
+}
+
+// detached is an Option starting the command in a new session,
+// with its output written to the given files, where not nil
+func detached(stdout, stderr *os.File) Option {
+	return func(s *command) {
+		newSession()(s)
+		s.beforeExec = append(s.beforeExec, func(c *exec.Cmd) {
+			c.Stdin = nil
+			c.Stdout = nil
+			c.Stderr = nil
+			if stdout != nil {
+				c.Stdout = stdout
+			}
+			if stderr != nil {
+				c.Stderr = stderr
+			}
+		})
+	}
+}
+
+// newSession is an Option starting the command in a new session
+func newSession() Option {
+	return func(s *command) {
+		s.beforeExec = append(s.beforeExec, func(c *exec.Cmd) {
+			if c.SysProcAttr == nil {
+				c.SysProcAttr = &syscall.SysProcAttr{}
+			}
+
+			// a session leader cannot also move process group
+			c.SysProcAttr.Setpgid = false
+			c.SysProcAttr.Setsid = true
+		})
+	}
+}
+
+// openDetachedOutput opens the path for appending, or returns nil if empty
+func openDetachedOutput(path string) (*os.File, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+}
@@ -0,0 +1,15 @@
+package shell
+
+import "fmt"
+
+// Detach launches the command so that it keeps running after
+// the current program exits. It is not supported on Windows.
+func Detach(command string, stdout, stderr string, options ...Option) (*Detached, error) {
+	return nil, fmt.Errorf("Detach: %w", ErrUnsupported)
+}
+
+// Daemon launches the command as a daemon via a double fork.
+// It is not supported on Windows.
+func Daemon(command string, stdout, stderr string, options ...Option) (*Detached, error) {
+	return nil, fmt.Errorf("Daemon: %w", ErrUnsupported)
+}
@@ -0,0 +1,199 @@
+package shell
+
+import (
+	"io"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Charset is a character encoding of command output
+type Charset int
+
+// The supported character encodings
+const (
+	UTF8        Charset = iota // validated, with any leading byte order mark dropped
+	Latin1                     // ISO 8859-1
+	Windows1252                // the Windows code page 1252
+	UTF16LE                    // as written by many Windows tools
+	UTF16BE
+)
+
+// InvalidPolicy decides what becomes of bytes invalid in a Charset
+type InvalidPolicy int
+
+// The policies for invalid bytes
+const (
+	ReplaceInvalid InvalidPolicy = iota // each run of invalid bytes becomes U+FFFD
+	DropInvalid                         // invalid bytes are left out
+)
+
+// Encoding is an Option to decode the command's output from the
+// charset to UTF-8 before it is split into lines or passed on to any
+// other destination. Bytes invalid in the charset are handled as
+// the policy decides.
+func Encoding(charset Charset, invalid InvalidPolicy) Option {
+	return func(s *command) {
+		s.encoding = &encoding{charset: charset, invalid: invalid}
+	}
+}
+
+// encoding is the decoding requested for the command's output
+type encoding struct {
+	charset  Charset
+	invalid  InvalidPolicy
+	decoders []*decoder
+}
+
+// wrap returns a decoder writing to w, or nil if w is nil
+func (e *encoding) wrap(w io.Writer) io.Writer {
+	if w == nil {
+		return nil
+	}
+	d := newDecoder(w, e.charset, e.invalid)
+	e.decoders = append(e.decoders, d)
+	return d
+}
+
+// flush ends the output of each decoder
+func (e *encoding) flush() {
+	for _, d := range e.decoders {
+		d.flush()
+	}
+}
+
+// windows1252 maps the bytes 0x80 to 0x9f of code page 1252,
+// which differs from Latin-1 in these only. Zero is undefined.
+var windows1252 = [32]rune{
+	0x20ac, 0, 0x201a, 0x0192, 0x201e, 0x2026, 0x2020, 0x2021,
+	0x02c6, 0x2030, 0x0160, 0x2039, 0x0152, 0, 0x017d, 0,
+	0, 0x2018, 0x2019, 0x201c, 0x201d, 0x2022, 0x2013, 0x2014,
+	0x02dc, 0x2122, 0x0161, 0x203a, 0x0153, 0, 0x017e, 0x0178,
+}
+
+// ------------------------------------------------------------------
+
+// decoder converts what is written to it to UTF-8, before passing
+// it on to w. A character split across writes is held back until
+// complete.
+type decoder struct {
+	mu      sync.Mutex
+	w       io.Writer
+	charset Charset
+	invalid InvalidPolicy
+	pending []byte // the start of an incomplete character
+	started bool   // anything has been decoded
+	bad     bool   // the last character decoded was invalid
+}
+
+func newDecoder(w io.Writer, charset Charset, invalid InvalidPolicy) *decoder {
+	return &decoder{w: w, charset: charset, invalid: invalid}
+}
+
+func (d *decoder) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	data := append(d.pending, p...)
+	out, n := d.decode(data, make([]byte, 0, len(data)))
+	d.pending = append([]byte{}, data[n:]...)
+
+	if len(out) > 0 {
+		if _, err := d.w.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// flush treats any incomplete character left at the end as invalid
+func (d *decoder) flush() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.pending) == 0 {
+		return
+	}
+	d.pending = nil
+	if out := d.rune(nil, utf8.RuneError, false); len(out) > 0 {
+		d.w.Write(out)
+	}
+}
+
+// decode appends the characters in data to out, returning it and
+// the number of bytes consumed, leaving out an incomplete character
+func (d *decoder) decode(data, out []byte) ([]byte, int) {
+	i := 0
+	for i < len(data) {
+		var r rune
+		size := 1
+		valid := true
+
+		switch d.charset {
+		case Latin1:
+			r = rune(data[i])
+		case Windows1252:
+			r = rune(data[i])
+			if r >= 0x80 && r < 0xa0 {
+				r = windows1252[r-0x80]
+				valid = r != 0
+			}
+		case UTF16LE, UTF16BE:
+			if len(data)-i < 2 {
+				return out, i
+			}
+			r = d.unit(data[i:])
+			size = 2
+			if utf16.IsSurrogate(r) {
+				if len(data)-i < 4 {
+					return out, i
+				}
+				r = utf16.DecodeRune(r, d.unit(data[i+2:]))
+				if r == utf8.RuneError {
+					// keep the second unit, which may start a valid pair
+					valid = false
+				} else {
+					size = 4
+				}
+			}
+		default:
+			if !utf8.FullRune(data[i:]) {
+				return out, i
+			}
+			r, size = utf8.DecodeRune(data[i:])
+			valid = r != utf8.RuneError || size > 1
+		}
+
+		i += size
+		if !d.started && r == 0xfeff && (d.charset == UTF8 || d.charset == UTF16LE || d.charset == UTF16BE) {
+			d.started = true
+			continue
+		}
+		d.started = true
+		out = d.rune(out, r, valid)
+	}
+	return out, i
+}
+
+// unit reads a UTF-16 code unit
+func (d *decoder) unit(b []byte) rune {
+	if d.charset == UTF16BE {
+		return rune(b[0])<<8 | rune(b[1])
+	}
+	return rune(b[1])<<8 | rune(b[0])
+}
+
+// rune appends r to out, or handles it as invalid
+func (d *decoder) rune(out []byte, r rune, valid bool) []byte {
+	if valid {
+		d.bad = false
+		return append(out, string(r)...)
+	}
+
+	// a run of invalid bytes is replaced only once
+	if d.invalid == ReplaceInvalid && !d.bad {
+		out = append(out, string(utf8.RuneError)...)
+	}
+	d.bad = true
+	return out
+}
@@ -0,0 +1,55 @@
+package shell
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodingOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmd     string
+		charset Charset
+		invalid InvalidPolicy
+		expect  string
+	}{
+		{"latin-1", `printf 'caf\xe9\n'`, Latin1, ReplaceInvalid, "café"},
+		{"windows-1252", `printf '\x80 \x93ok\x94\n'`, Windows1252, ReplaceInvalid, "€ “ok”"},
+		{"utf-16le with bom", `printf '\xff\xfeh\0\xe9\0\n\0'`, UTF16LE, ReplaceInvalid, "hé"},
+		{"utf-16be surrogate pair", `printf '\0a\xd8\x3d\xde\x00\0\n'`, UTF16BE, ReplaceInvalid, "a😀"},
+		{"invalid utf-8 replaced", `printf 'a\xff\xfeb\n'`, UTF8, ReplaceInvalid, "a�b"},
+		{"invalid utf-8 dropped", `printf 'a\xff\xfeb\n'`, UTF8, DropInvalid, "ab"},
+		{"truncated utf-8 at end", `printf 'a\xc3'`, UTF8, ReplaceInvalid, "a�"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Run(tt.cmd, Encoding(tt.charset, tt.invalid))
+			if got := r.FullStdout().Text(); got != tt.expect {
+				t.Errorf("Expected %q, got %q", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestDecoderSplitWrites(t *testing.T) {
+	var out bytes.Buffer
+	d := newDecoder(&out, UTF16LE, ReplaceInvalid)
+
+	// "é😀" split at every byte
+	for _, b := range []byte{0xe9, 0x00, 0x3d, 0xd8, 0x00, 0xde} {
+		d.Write([]byte{b})
+	}
+	d.flush()
+
+	if got := out.String(); got != "é😀" {
+		t.Errorf("Expected characters split across writes to be decoded, got %q", got)
+	}
+
+	out.Reset()
+	d = newDecoder(&out, UTF16LE, ReplaceInvalid)
+	d.Write([]byte{0x3d, 0xd8, 'a', 0x00})
+	if got := out.String(); got != "�a" {
+		t.Errorf("Expected an unpaired surrogate to be replaced, got %q", got)
+	}
+}
@@ -0,0 +1,56 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ExitError is returned by Result.Err, with the FailOnNonZero
+// Option, for a command that exited with a non-zero code
+type ExitError struct {
+	ExitCode int
+	Stderr   string // the last few lines of stderr
+}
+
+func (e *ExitError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("exit code %d", e.ExitCode)
+	}
+	lines := strings.Split(e.Stderr, "\n")
+	return fmt.Sprintf("exit code %d: %s", e.ExitCode, lines[len(lines)-1])
+}
+
+// FailOnNonZero is an Option to have Result.Err, and so IsError,
+// report a non-zero exit code as an ExitError, so that checking
+// the error is enough to know if the command succeeded
+func FailOnNonZero() Option {
+	return func(s *command) {
+		s.Result.failOnNonZero = true
+	}
+}
+
+// exitError returns the ExitError for the command,
+// or nil if it is not done, or exited with code 0
+func (r *Result) exitError() error {
+	if r.status().StopTs == 0 {
+		return nil
+	}
+
+	code := r.ExitCode()
+	if code == 0 {
+		return nil
+	}
+
+	lines := r.stderrLines()
+	if len(lines) > stderrTail {
+		lines = lines[len(lines)-stderrTail:]
+	}
+	return &ExitError{ExitCode: code, Stderr: strings.Join(lines, "\n")}
+}
+
+// isExitError indicates if err is, or wraps, an ExitError
+func isExitError(err error) bool {
+	var exitErr *ExitError
+	return errors.As(err, &exitErr)
+}
@@ -0,0 +1,37 @@
+package shell
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFailOnNonZero(t *testing.T) {
+	r := Run("echo first >&2; echo oops >&2; exit 3", FailOnNonZero())
+
+	var exitErr *ExitError
+	if !errors.As(r.Err(), &exitErr) {
+		t.Fatalf("Expected an ExitError, got %v", r.Err())
+	}
+	if exitErr.ExitCode != 3 || exitErr.Stderr != "first\noops" {
+		t.Errorf("Unexpected ExitError: %#v", exitErr)
+	}
+	if msg := exitErr.Error(); msg != "exit code 3: oops" {
+		t.Errorf("Unexpected message: %q", msg)
+	}
+	if !r.IsError() || !failed(r) {
+		t.Error("Expected the Result to be an error")
+	}
+	if class := exitClass(r); class != "failed" {
+		t.Errorf("Expected the command to count as failed, got %q", class)
+	}
+
+	if r := Run("exit 3"); r.Err() != nil {
+		t.Errorf("Expected no error without the Option, got %v", r.Err())
+	}
+	if r := Run("true", FailOnNonZero()); r.Err() != nil {
+		t.Errorf("Expected no error for a zero exit, got %v", r.Err())
+	}
+	if r := Run("exit 4", FailOnNonZero()); r.Err().Error() != "exit code 4" {
+		t.Errorf("Unexpected error: %v", r.Err())
+	}
+}
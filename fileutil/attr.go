@@ -0,0 +1,17 @@
+package fileutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Error describes an external tool, such as chattr, that failed
+type Error struct {
+	Args     []string // the tool and its arguments
+	ExitCode int
+	Stderr   string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: exit code %d: %s", strings.Join(e.Args, " "), e.ExitCode, e.Stderr)
+}
@@ -0,0 +1,74 @@
+package fileutil
+
+import (
+	"strings"
+
+	"github.com/brinick/shell"
+)
+
+// SetImmutable sets, or clears, the immutable attribute of path with
+// chattr(1). An immutable file can not be changed, renamed, deleted
+// or linked to, even by root, until the attribute is cleared, which
+// needs the CAP_LINUX_IMMUTABLE capability.
+func SetImmutable(path string, immutable bool, options ...shell.Option) error {
+	return chattr(path, 'i', immutable, options)
+}
+
+// IsImmutable indicates if path has the immutable attribute set
+func IsImmutable(path string, options ...shell.Option) (bool, error) {
+	return hasAttr(path, 'i', options)
+}
+
+// SetAppendOnly sets, or clears, the append-only attribute of path
+// with chattr(1). An append-only file can only be opened for appending,
+// and not otherwise changed, renamed or deleted.
+func SetAppendOnly(path string, appendOnly bool, options ...shell.Option) error {
+	return chattr(path, 'a', appendOnly, options)
+}
+
+// IsAppendOnly indicates if path has the append-only attribute set
+func IsAppendOnly(path string, options ...shell.Option) (bool, error) {
+	return hasAttr(path, 'a', options)
+}
+
+// chattr sets or clears the attribute
+func chattr(path string, attr byte, set bool, options []shell.Option) error {
+	op := "-"
+	if set {
+		op = "+"
+	}
+	_, err := run("chattr", []string{op + string(attr), "--", path}, options)
+	return err
+}
+
+// hasAttr indicates if lsattr(1) lists the attribute for path
+func hasAttr(path string, attr byte, options []shell.Option) (bool, error) {
+	res, err := run("lsattr", []string{"-d", "--", path}, options)
+	if err != nil {
+		return false, err
+	}
+
+	// the attributes come first, one letter each, e.g. "----i----"
+	fields := strings.Fields(res.FullStdout().Text())
+	if len(fields) == 0 {
+		return false, nil
+	}
+	return strings.IndexByte(fields[0], attr) >= 0, nil
+}
+
+// run runs the tool, returning an Error if it fails
+func run(tool string, args []string, options []shell.Option) (*shell.Result, error) {
+	res := shell.RunArgs(tool, args, options...)
+	if res.IsError() {
+		return res, res.Err()
+	}
+
+	if res.ExitCode() != 0 {
+		return res, &Error{
+			Args:     append([]string{tool}, args...),
+			ExitCode: res.ExitCode(),
+			Stderr:   res.FullStderr().Text(),
+		}
+	}
+	return res, nil
+}
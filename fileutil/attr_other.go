@@ -0,0 +1,30 @@
+//go:build !linux
+// +build !linux
+
+package fileutil
+
+import (
+	"fmt"
+
+	"github.com/brinick/shell"
+)
+
+// SetImmutable is only supported on Linux
+func SetImmutable(path string, immutable bool, options ...shell.Option) error {
+	return fmt.Errorf("SetImmutable: %w", shell.ErrUnsupported)
+}
+
+// IsImmutable is only supported on Linux
+func IsImmutable(path string, options ...shell.Option) (bool, error) {
+	return false, fmt.Errorf("IsImmutable: %w", shell.ErrUnsupported)
+}
+
+// SetAppendOnly is only supported on Linux
+func SetAppendOnly(path string, appendOnly bool, options ...shell.Option) error {
+	return fmt.Errorf("SetAppendOnly: %w", shell.ErrUnsupported)
+}
+
+// IsAppendOnly is only supported on Linux
+func IsAppendOnly(path string, options ...shell.Option) (bool, error) {
+	return false, fmt.Errorf("IsAppendOnly: %w", shell.ErrUnsupported)
+}
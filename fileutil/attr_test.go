@@ -0,0 +1,98 @@
+//go:build linux
+// +build linux
+
+package fileutil_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/brinick/shell/fileutil"
+)
+
+// attrFile creates a file whose attributes can be changed, or skips the test
+func attrFile(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("chattr"); err != nil {
+		t.Skip("chattr is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "attr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(path, []byte("data\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		fileutil.SetImmutable(path, false)
+		fileutil.SetAppendOnly(path, false)
+		os.RemoveAll(dir)
+	})
+
+	if err := fileutil.SetImmutable(path, false); err != nil {
+		t.Skipf("attributes not supported here: %v", err)
+	}
+	return path
+}
+
+func TestImmutable(t *testing.T) {
+	path := attrFile(t)
+	if err := fileutil.SetImmutable(path, true); err != nil {
+		t.Skipf("unable to set the immutable attribute: %v", err)
+	}
+
+	if on, err := fileutil.IsImmutable(path); err != nil || !on {
+		t.Fatalf("Expected the file to be immutable, got %v, %v", on, err)
+	}
+	if err := os.Remove(path); err == nil {
+		t.Fatal("Expected an immutable file not to be removable")
+	}
+
+	if err := fileutil.SetImmutable(path, false); err != nil {
+		t.Fatal(err)
+	}
+	if on, err := fileutil.IsImmutable(path); err != nil || on {
+		t.Errorf("Expected the file not to be immutable, got %v, %v", on, err)
+	}
+}
+
+func TestAppendOnly(t *testing.T) {
+	path := attrFile(t)
+	if err := fileutil.SetAppendOnly(path, true); err != nil {
+		t.Skipf("unable to set the append-only attribute: %v", err)
+	}
+
+	if on, err := fileutil.IsAppendOnly(path); err != nil || !on {
+		t.Fatalf("Expected the file to be append-only, got %v, %v", on, err)
+	}
+	if on, err := fileutil.IsImmutable(path); err != nil || on {
+		t.Errorf("Expected the file not to be immutable, got %v, %v", on, err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("Expected appending to be allowed: %v", err)
+	}
+	f.Close()
+	if err := ioutil.WriteFile(path, nil, 0o644); err == nil {
+		t.Error("Expected truncating an append-only file to fail")
+	}
+}
+
+func TestIsImmutableMissing(t *testing.T) {
+	if _, err := exec.LookPath("lsattr"); err != nil {
+		t.Skip("lsattr is not installed")
+	}
+
+	_, err := fileutil.IsImmutable(filepath.Join(os.TempDir(), "no-such-file"))
+	var ferr *fileutil.Error
+	if !errors.As(err, &ferr) || ferr.ExitCode == 0 {
+		t.Errorf("Expected an Error, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+// Package fileutil provides helpers for inspecting
+// and managing files and directory trees.
+package fileutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Violation names a rule an entry breaks
+type Violation string
+
+// The violations reported by AuditTree
+const (
+	WorldWritable Violation = "world-writable"
+	Setuid        Violation = "setuid"
+	Setgid        Violation = "setgid"
+	WrongOwner    Violation = "wrong owner"
+	WrongGroup    Violation = "wrong group"
+)
+
+// Rules selects what AuditTree reports
+type Rules struct {
+	// WorldWritable reports entries anyone can write to. Directories
+	// with the sticky bit set, such as /tmp, are not reported.
+	WorldWritable bool
+
+	// Setuid reports entries with the setuid or setgid bit set
+	Setuid bool
+
+	// Owners and Groups list the uids and gids entries may belong
+	// to. Either is ignored if empty, and on Windows.
+	Owners []int
+	Groups []int
+
+	// OnError decides what becomes of errors reading the tree
+	OnError ErrorPolicy
+}
+
+// ErrorPolicy decides what becomes of errors walking a tree
+type ErrorPolicy int
+
+// The policies for walk errors
+const (
+	AbortOnError  ErrorPolicy = iota // the walk stops at the first error
+	CollectErrors                    // the walk goes on, and the errors are returned as a *WalkErrors
+)
+
+// WalkErrors collects the errors met walking a tree under
+// CollectErrors. The entries that could be read were still
+// checked, so the findings returned alongside remain usable.
+type WalkErrors struct {
+	Errors []*os.PathError // in the order they were met
+	Walked int             // the number of entries checked
+}
+
+func (e *WalkErrors) Error() string {
+	if len(e.Errors) == 1 {
+		return fmt.Sprintf("%v (%d entries checked)", e.Errors[0], e.Walked)
+	}
+	return fmt.Sprintf("%v and %d more errors (%d entries checked)", e.Errors[0], len(e.Errors)-1, e.Walked)
+}
+
+// Unwrap returns the errors for errors.Is and errors.As
+func (e *WalkErrors) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// add records the error met at path
+func (e *WalkErrors) add(path string, err error) {
+	var pe *os.PathError
+	if !errors.As(err, &pe) {
+		pe = &os.PathError{Op: "walk", Path: path, Err: err}
+	}
+	e.Errors = append(e.Errors, pe)
+}
+
+// Finding describes an entry breaking one or more rules
+type Finding struct {
+	Path       string
+	Mode       os.FileMode
+	UID, GID   int // -1 on Windows
+	Violations []Violation
+}
+
+// AuditTree walks the tree under root, root included, and returns
+// the entries breaking the rules, in lexical order. Symbolic links
+// are not followed, and are only checked for ownership. Errors
+// reading the tree are handled as rules.OnError decides.
+func AuditTree(root string, rules Rules) ([]Finding, error) {
+	var (
+		findings []Finding
+		errs     WalkErrors
+	)
+	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if rules.OnError != CollectErrors {
+				return err
+			}
+			errs.add(path, err)
+			if fi == nil {
+				return nil
+			}
+			// a directory that cannot be read is still checked itself
+		}
+		errs.Walked++
+		if f := audit(path, fi, rules); len(f.Violations) > 0 {
+			findings = append(findings, f)
+		}
+		return nil
+	})
+	if err == nil && len(errs.Errors) > 0 {
+		err = &errs
+	}
+	return findings, err
+}
+
+// audit checks a single entry against the rules
+func audit(path string, fi os.FileInfo, rules Rules) Finding {
+	mode := fi.Mode()
+	uid, gid := owner(fi)
+	f := Finding{Path: path, Mode: mode, UID: uid, GID: gid}
+
+	if mode&os.ModeSymlink == 0 {
+		sticky := mode.IsDir() && mode&os.ModeSticky != 0
+		if rules.WorldWritable && mode.Perm()&0o002 != 0 && !sticky {
+			f.Violations = append(f.Violations, WorldWritable)
+		}
+		if rules.Setuid && mode&os.ModeSetuid != 0 {
+			f.Violations = append(f.Violations, Setuid)
+		}
+		if rules.Setuid && mode&os.ModeSetgid != 0 {
+			f.Violations = append(f.Violations, Setgid)
+		}
+	}
+
+	if uid >= 0 && len(rules.Owners) > 0 && !contains(rules.Owners, uid) {
+		f.Violations = append(f.Violations, WrongOwner)
+	}
+	if gid >= 0 && len(rules.Groups) > 0 && !contains(rules.Groups, gid) {
+		f.Violations = append(f.Violations, WrongGroup)
+	}
+	return f
+}
+
+func contains(ids []int, id int) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package fileutil_test
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/brinick/shell/fileutil"
+)
+
+// write creates the file with the given mode, whatever the umask
+func write(t *testing.T, path string, mode os.FileMode) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuditTree(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not meaningful on Windows")
+	}
+
+	root, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	shared := filepath.Join(root, "shared")
+	if err := os.Mkdir(shared, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(shared, 0o777|os.ModeSticky); err != nil {
+		t.Fatal(err)
+	}
+
+	write(t, filepath.Join(root, "ok"), 0o644)
+	write(t, filepath.Join(root, "open"), 0o666)
+	write(t, filepath.Join(root, "suid"), 0o755|os.ModeSetuid)
+	write(t, filepath.Join(shared, "both"), 0o777|os.ModeSetuid|os.ModeSetgid)
+	if err := os.Symlink("ok", filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := fileutil.AuditTree(root, fileutil.Rules{WorldWritable: true, Setuid: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string][]fileutil.Violation{}
+	for _, f := range findings {
+		rel, _ := filepath.Rel(root, f.Path)
+		got[rel] = f.Violations
+		if f.UID != os.Getuid() {
+			t.Errorf("%s: expected uid %d, got %d", rel, os.Getuid(), f.UID)
+		}
+	}
+
+	want := map[string][]fileutil.Violation{
+		"open":                          {fileutil.WorldWritable},
+		"suid":                          {fileutil.Setuid},
+		filepath.Join("shared", "both"): {fileutil.WorldWritable, fileutil.Setuid, fileutil.Setgid},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestAuditTreeOwners(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ownership is not checked on Windows")
+	}
+
+	root, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	write(t, filepath.Join(root, "file"), 0o644)
+
+	findings, err := fileutil.AuditTree(root, fileutil.Rules{Owners: []int{os.Getuid()}, Groups: []int{os.Getgid()}})
+	if err != nil || len(findings) != 0 {
+		t.Fatalf("Expected no findings, got %v, %v", findings, err)
+	}
+
+	findings, err = fileutil.AuditTree(root, fileutil.Rules{Owners: []int{os.Getuid() + 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 2 || !reflect.DeepEqual(findings[1].Violations, []fileutil.Violation{fileutil.WrongOwner}) {
+		t.Errorf("Expected the root and file to have the wrong owner, got %v", findings)
+	}
+}
+
+func TestAuditTreeMissing(t *testing.T) {
+	if _, err := fileutil.AuditTree(filepath.Join(os.TempDir(), "no-such-tree"), fileutil.Rules{}); !os.IsNotExist(err) {
+		t.Errorf("Expected a not-exist error, got %v", err)
+	}
+}
+
+func TestAuditTreeCollectErrors(t *testing.T) {
+	missing := filepath.Join(os.TempDir(), "no-such-tree")
+	_, err := fileutil.AuditTree(missing, fileutil.Rules{OnError: fileutil.CollectErrors})
+
+	var errs *fileutil.WalkErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected a *WalkErrors, got %v", err)
+	}
+	if len(errs.Errors) != 1 || errs.Errors[0].Path != missing || errs.Walked != 0 {
+		t.Errorf("Expected one error for %s and nothing walked, got %+v", missing, errs)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected the error to wrap fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestAuditTreeUnreadable(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("needs directory permissions that apply to the user")
+	}
+
+	root, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	locked := filepath.Join(root, "locked")
+	if err := os.Mkdir(locked, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	write(t, filepath.Join(locked, "hidden"), 0o666)
+	write(t, filepath.Join(root, "open"), 0o666)
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(locked, 0o700)
+
+	rules := fileutil.Rules{WorldWritable: true}
+	if _, err := fileutil.AuditTree(root, rules); !os.IsPermission(err) {
+		t.Errorf("Expected the walk to stop at a permission error, got %v", err)
+	}
+
+	rules.OnError = fileutil.CollectErrors
+	findings, err := fileutil.AuditTree(root, rules)
+	var errs *fileutil.WalkErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected a *WalkErrors, got %v", err)
+	}
+	if len(errs.Errors) != 1 || errs.Errors[0].Path != locked || errs.Walked != 3 {
+		t.Errorf("Expected one error for %s after 3 entries, got %+v", locked, errs)
+	}
+	if len(findings) != 1 || findings[0].Path != filepath.Join(root, "open") {
+		t.Errorf("Expected the readable entries to still be audited, got %v", findings)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package fileutil
+
+import (
+	"os"
+	"syscall"
+)
+
+// owner returns the uid and gid the entry belongs to
+func owner(fi os.FileInfo) (int, int) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return -1, -1
+	}
+	return int(st.Uid), int(st.Gid)
+}
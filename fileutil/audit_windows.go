@@ -0,0 +1,8 @@
+package fileutil
+
+import "os"
+
+// owner is unknown on Windows, which has no uids or gids
+func owner(fi os.FileInfo) (int, int) {
+	return -1, -1
+}
@@ -0,0 +1,236 @@
+package fileutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxSymlinks is the number of symbolic links followed
+// in resolving a path before giving up, as Linux does
+const maxSymlinks = 40
+
+var (
+	// ErrEscapesRoot is returned, wrapped, for a path
+	// that resolves to somewhere outside the root
+	ErrEscapesRoot = errors.New("path escapes root")
+
+	// ErrIsRoot is returned, wrapped, on attempting
+	// to remove the root itself
+	ErrIsRoot = errors.New("path is the root")
+
+	// ErrTooManyLinks is returned, wrapped, for a path whose
+	// resolution follows too many symbolic links, as in a loop
+	ErrTooManyLinks = errors.New("too many levels of symbolic links")
+)
+
+// Root scopes file operations to a directory tree: paths given to
+// its methods are relative to the root, and are refused if they lead
+// outside it, through ".." or a symbolic link. A symbolic link to an
+// absolute path is taken to be relative to the root, as in a chroot.
+//
+// Paths are resolved before being operated on, so a tree being
+// changed concurrently by someone else can still race the checks.
+type Root struct {
+	root string
+}
+
+// Rooted returns the Root for the existing directory root
+func Rooted(root string) (*Root, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if abs, err = filepath.EvalSymlinks(abs); err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, &os.PathError{Op: "rooted", Path: root, Err: errors.New("not a directory")}
+	}
+	return &Root{root: abs}, nil
+}
+
+// Path returns the absolute path of the root
+func (r *Root) Path() string {
+	return r.root
+}
+
+// Resolve returns the absolute path that path leads to, following
+// any symbolic links. The final element need not exist, nor those
+// before it, but only the existing ones are checked for links.
+func (r *Root) Resolve(path string) (string, error) {
+	resolved, err := r.resolve(path)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", path, err)
+	}
+	return resolved, nil
+}
+
+// Open opens the file at path for reading
+func (r *Root) Open(path string) (*os.File, error) {
+	resolved, err := r.Resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(resolved)
+}
+
+// Stat returns the FileInfo of the file at path,
+// following any final symbolic link
+func (r *Root) Stat(path string) (os.FileInfo, error) {
+	resolved, err := r.Resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(resolved)
+}
+
+// Remove removes the file, or empty directory, at path. A symbolic
+// link as the final element is removed itself, not its target.
+func (r *Root) Remove(path string) error {
+	target, err := r.parentResolved(path)
+	if err != nil {
+		return err
+	}
+	return os.Remove(target)
+}
+
+// RemoveAll removes path and anything it contains, without following
+// symbolic links within it. It returns nil if path does not exist.
+func (r *Root) RemoveAll(path string) error {
+	target, err := r.parentResolved(path)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(target)
+}
+
+// Glob returns the paths, relative to the root, matching the pattern
+// as for filepath.Match. A matching symbolic link is returned whatever
+// its target, as Remove would remove the link itself.
+func (r *Root) Glob(pattern string) ([]string, error) {
+	rel := filepath.Clean(strings.TrimLeft(pattern, string(filepath.Separator)))
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("glob %s: %w", pattern, ErrEscapesRoot)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(r.root, rel))
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, m := range matches {
+		rel, err := filepath.Rel(r.root, m)
+		if err != nil {
+			continue
+		}
+		if _, err := r.parentResolved(rel); err != nil {
+			continue
+		}
+		paths = append(paths, rel)
+	}
+	return paths, nil
+}
+
+// parentResolved resolves the directory holding path, leaving its
+// final element as is, and refuses the root itself
+func (r *Root) parentResolved(path string) (string, error) {
+	trimmed := strings.TrimRight(path, string(filepath.Separator))
+	base := filepath.Base(trimmed)
+
+	var resolved string
+	var err error
+	if trimmed == "" || base == "." || base == ".." {
+		// the final element is a directory, to be resolved in full
+		resolved, err = r.resolve(trimmed)
+	} else {
+		resolved, err = r.resolve(trimmed[:len(trimmed)-len(base)])
+		resolved = filepath.Join(resolved, base)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", path, err)
+	}
+	if resolved == r.root {
+		return "", fmt.Errorf("%s: %w", path, ErrIsRoot)
+	}
+	return resolved, nil
+}
+
+// resolve follows path from the root one element at a time,
+// expanding symbolic links and refusing to step outside the root
+func (r *Root) resolve(path string) (string, error) {
+	current := r.root
+	remaining := path
+	links := 0
+	// elements below current that do not exist yet; a ".." may
+	// only cancel one of these, never an existing component
+	var missing []string
+
+	for remaining != "" {
+		var elem string
+		i := strings.IndexRune(remaining, filepath.Separator)
+		if i < 0 {
+			elem, remaining = remaining, ""
+		} else {
+			elem, remaining = remaining[:i], remaining[i+1:]
+		}
+
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			if len(missing) > 0 {
+				missing = missing[:len(missing)-1]
+				continue
+			}
+			if current == r.root {
+				return "", ErrEscapesRoot
+			}
+			current = filepath.Dir(current)
+			continue
+		}
+
+		if len(missing) > 0 {
+			missing = append(missing, elem)
+			continue
+		}
+
+		next := filepath.Join(current, elem)
+		fi, err := os.Lstat(next)
+		if os.IsNotExist(err) {
+			missing = append(missing, elem)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if fi.Mode()&os.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+
+		links++
+		if links > maxSymlinks {
+			return "", ErrTooManyLinks
+		}
+		target, err := os.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(target) {
+			current = r.root
+		}
+		remaining = target + string(filepath.Separator) + remaining
+	}
+	return filepath.Join(append([]string{current}, missing...)...), nil
+}
@@ -0,0 +1,177 @@
+package fileutil_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/brinick/shell/fileutil"
+)
+
+// sandbox creates a root holding a few files and links, next to a
+// file outside it that must never be reached
+func sandbox(t *testing.T) (*fileutil.Root, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges on Windows")
+	}
+
+	dir, err := ioutil.TempDir("", "rooted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	outside := filepath.Join(dir, "outside")
+	root := filepath.Join(dir, "root")
+	for _, d := range []string{filepath.Join(root, "logs", "old"), filepath.Join(root, "etc")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{outside, filepath.Join(root, "logs", "a.log"), filepath.Join(root, "logs", "old", "b.log"), filepath.Join(root, "etc", "passwd")} {
+		if err := ioutil.WriteFile(f, []byte(f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	links := map[string]string{
+		"logs/escape":   "../../outside",
+		"logs/absolute": "/etc/passwd",
+		"logs/current":  "old",
+		"logs/loop":     "loop",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := fileutil.Rooted(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, outside
+}
+
+func TestRootedResolve(t *testing.T) {
+	r, _ := sandbox(t)
+
+	tests := map[string]string{
+		"logs/a.log":          "logs/a.log",
+		"/logs/a.log":         "logs/a.log",
+		"logs/../etc/passwd":  "etc/passwd",
+		"logs/current/b.log":  "logs/old/b.log",
+		"logs/absolute":       "etc/passwd",
+		"logs/new/../c.log":   "logs/c.log",
+		"logs/missing/x/y.go": "logs/missing/x/y.go",
+	}
+	for path, want := range tests {
+		got, err := r.Resolve(path)
+		if err != nil || got != filepath.Join(r.Path(), want) {
+			t.Errorf("Resolve(%q) = %q, %v, want %q", path, got, err, want)
+		}
+	}
+
+	escapes := []string{"..", "logs/../../outside", "logs/escape", "logs/missing/../../../x"}
+	for _, path := range escapes {
+		if got, err := r.Resolve(path); !errors.Is(err, fileutil.ErrEscapesRoot) {
+			t.Errorf("Resolve(%q) = %q, %v, want ErrEscapesRoot", path, got, err)
+		}
+	}
+
+	if _, err := r.Resolve("logs/loop"); !errors.Is(err, fileutil.ErrTooManyLinks) {
+		t.Errorf("Expected a link loop to be refused, got %v", err)
+	}
+}
+
+func TestRootedResolveAfterMissing(t *testing.T) {
+	r, outside := sandbox(t)
+
+	// a link out of the root, reached after stepping back from a
+	// component that does not exist
+	dir := filepath.Join(filepath.Dir(outside), "outdir")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	victim := filepath.Join(dir, "victim")
+	if err := ioutil.WriteFile(victim, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../outdir", filepath.Join(r.Path(), "evil")); err != nil {
+		t.Fatal(err)
+	}
+
+	path := "missing/../evil/victim"
+	if got, err := r.Resolve(path); !errors.Is(err, fileutil.ErrEscapesRoot) {
+		t.Errorf("Resolve(%q) = %q, %v, want ErrEscapesRoot", path, got, err)
+	}
+	if f, err := r.Open(path); !errors.Is(err, fileutil.ErrEscapesRoot) {
+		if f != nil {
+			f.Close()
+		}
+		t.Errorf("Expected opening %q to be refused, got %v", path, err)
+	}
+	if err := r.Remove(path); !errors.Is(err, fileutil.ErrEscapesRoot) {
+		t.Errorf("Expected removing %q to be refused, got %v", path, err)
+	}
+	if _, err := os.Stat(victim); err != nil {
+		t.Errorf("Expected the file outside the root to survive: %v", err)
+	}
+}
+
+func TestRootedRemove(t *testing.T) {
+	r, outside := sandbox(t)
+
+	if err := r.RemoveAll("logs/escape/../outside"); !errors.Is(err, fileutil.ErrEscapesRoot) {
+		t.Errorf("Expected removing through a link out of root to be refused, got %v", err)
+	}
+	if err := r.RemoveAll("/"); !errors.Is(err, fileutil.ErrIsRoot) {
+		t.Errorf("Expected removing the root to be refused, got %v", err)
+	}
+
+	// the link itself is removed, not its target
+	if err := r.Remove("logs/escape"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("Expected the file outside the root to survive: %v", err)
+	}
+
+	if err := r.RemoveAll("logs/current/.."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(r.Path(), "logs")); !os.IsNotExist(err) {
+		t.Errorf("Expected logs to be removed, got %v", err)
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("Expected the file outside the root to survive: %v", err)
+	}
+}
+
+func TestRootedGlob(t *testing.T) {
+	r, _ := sandbox(t)
+
+	matches, err := r.Glob("logs/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"logs/a.log", "logs/absolute", "logs/current", "logs/escape", "logs/loop", "logs/old"}
+	if !reflect.DeepEqual(matches, want) {
+		t.Errorf("Expected %q, got %q", want, matches)
+	}
+
+	if matches, err := r.Glob("logs/../../*"); !errors.Is(err, fileutil.ErrEscapesRoot) {
+		t.Errorf("Expected a pattern out of the root to be refused, got %q, %v", matches, err)
+	}
+
+	if f, err := r.Open("logs/absolute"); err != nil {
+		t.Error(err)
+	} else {
+		f.Close()
+	}
+}
@@ -0,0 +1,170 @@
+package fileutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Snapshot is a copy of a directory tree made by SnapshotTree
+type Snapshot struct {
+	Root string // the tree snapshotted
+	Dir  string // where the snapshot is held
+}
+
+// SnapshotTree snapshots the tree under root into snapDir, which must
+// not exist, as "cp -al" would: directories and symbolic links are
+// recreated, but files are hard links to the originals, so taking the
+// snapshot is cheap. As a consequence, a file changed in place, rather
+// than replaced, changes in the snapshot too. snapDir must be on the
+// same filesystem as root, but not within it.
+func SnapshotTree(root, snapDir string) (*Snapshot, error) {
+	if _, err := os.Lstat(snapDir); err == nil {
+		return nil, fmt.Errorf("SnapshotTree: %s already exists", snapDir)
+	}
+
+	// a snapshot within the tree would be walked as it is written
+	within, err := isWithin(snapDir, root)
+	if err != nil {
+		return nil, fmt.Errorf("SnapshotTree: %v", err)
+	}
+	if within {
+		return nil, fmt.Errorf("SnapshotTree: %s is within %s", snapDir, root)
+	}
+	if err := linkTree(root, snapDir); err != nil {
+		os.RemoveAll(snapDir)
+		return nil, fmt.Errorf("SnapshotTree: %v", err)
+	}
+	return &Snapshot{Root: root, Dir: snapDir}, nil
+}
+
+// RollbackTo restores the tree snapshotted to its state in the
+// snapshot, which is kept, so it can be rolled back to again. The
+// restored tree is built next to the current one, which is then
+// swapped out and removed, so the tree is briefly missing, but
+// never half restored.
+func RollbackTo(s *Snapshot) error {
+	root := filepath.Clean(s.Root)
+	tmp, err := ioutil.TempDir(filepath.Dir(root), "."+filepath.Base(root)+".rollback-")
+	if err != nil {
+		return fmt.Errorf("RollbackTo: %v", err)
+	}
+
+	if err := linkTree(s.Dir, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("RollbackTo: %v", err)
+	}
+
+	old := tmp + ".old"
+	if err := os.Rename(root, old); err != nil && !os.IsNotExist(err) {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("RollbackTo: %v", err)
+	}
+	if err := os.Rename(tmp, root); err != nil {
+		os.Rename(old, root)
+		os.RemoveAll(tmp)
+		return fmt.Errorf("RollbackTo: %v", err)
+	}
+
+	if err := os.RemoveAll(old); err != nil {
+		return fmt.Errorf("RollbackTo: %v", err)
+	}
+	return nil
+}
+
+// isWithin indicates if path is dir, or below it, once both have
+// their symbolic links resolved. Parts of path need not exist.
+func isWithin(path, dir string) (bool, error) {
+	dir, err := realPath(dir)
+	if err != nil {
+		return false, err
+	}
+	path, err = realPath(path)
+	if err != nil {
+		return false, err
+	}
+
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false, err
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)), nil
+}
+
+// realPath returns the absolute path with symbolic links resolved,
+// as far as it exists, and the missing elements appended as they are
+func realPath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			for i := len(missing) - 1; i >= 0; i-- {
+				resolved = filepath.Join(resolved, missing[i])
+			}
+			return resolved, nil
+		}
+		parent := filepath.Dir(path)
+		if !os.IsNotExist(err) || parent == path {
+			return "", err
+		}
+		missing = append(missing, filepath.Base(path))
+		path = parent
+	}
+}
+
+// linkTree recreates the tree under src at dst, hard linking files
+func linkTree(src, dst string) error {
+	// directories are made writable until populated,
+	// and only then given their own permissions
+	type dirMode struct {
+		path string
+		mode os.FileMode
+	}
+	var dirs []dirMode
+
+	err := filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch mode := fi.Mode(); {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirMode{target, mode.Perm() | mode&(os.ModeSticky|os.ModeSetgid)})
+		case mode&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			return os.Link(path, target)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].path, dirs[i].mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package fileutil_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/brinick/shell/fileutil"
+)
+
+func TestSnapshotTree(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges on Windows")
+	}
+
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "app")
+	if err := os.MkdirAll(filepath.Join(root, "conf"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	conf := filepath.Join(root, "conf", "app.conf")
+	if err := ioutil.WriteFile(conf, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("conf/app.conf", filepath.Join(root, "current.conf")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(root, "conf"), 0555); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(filepath.Join(root, "conf"), 0755)
+
+	snap, err := fileutil.SnapshotTree(root, filepath.Join(dir, "snap"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orig, _ := os.Stat(conf)
+	copied, err := os.Stat(filepath.Join(snap.Dir, "conf", "app.conf"))
+	if err != nil || !os.SameFile(orig, copied) {
+		t.Errorf("Expected the file to be hard linked, got %v", err)
+	}
+	if fi, err := os.Stat(filepath.Join(snap.Dir, "conf")); err != nil || fi.Mode().Perm() != 0555 {
+		t.Errorf("Expected the directory mode to be kept, got %v, %v", fi, err)
+	}
+	if link, err := os.Readlink(filepath.Join(snap.Dir, "current.conf")); err != nil || link != "conf/app.conf" {
+		t.Errorf("Expected the link to be recreated, got %q, %v", link, err)
+	}
+
+	// risky changes: the file is replaced, and another added
+	os.Chmod(filepath.Join(root, "conf"), 0755)
+	tmp := conf + ".new"
+	if err := ioutil.WriteFile(tmp, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, conf); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "junk"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fileutil.RollbackTo(snap); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(root, "current.conf")); string(data) != "v1" {
+		t.Errorf("Expected the file to be rolled back, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(root, "junk")); !os.IsNotExist(err) {
+		t.Errorf("Expected the added file to be gone, got %v", err)
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("Expected no rollback leftovers, got %d entries", len(entries))
+	}
+
+	if _, err := fileutil.SnapshotTree(root, snap.Dir); err == nil {
+		t.Error("Expected an existing snapshot directory to be refused")
+	}
+}
+
+func TestSnapshotTreeWithinRoot(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges on Windows")
+	}
+
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "app")
+	if err := os.Mkdir(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("app", filepath.Join(dir, "alias")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, snapDir := range []string{
+		filepath.Join(root, "snap"),
+		filepath.Join(root, "snaps", "1"),
+		filepath.Join(dir, "alias", "snap"),
+		filepath.Join(dir, "app", "..", "app", "snap"),
+	} {
+		if _, err := fileutil.SnapshotTree(root, snapDir); err == nil {
+			t.Errorf("Expected a snapshot at %s, within the tree, to be refused", snapDir)
+		}
+	}
+	if entries, _ := ioutil.ReadDir(root); len(entries) != 0 {
+		t.Errorf("Expected nothing to be written in the tree, found %d entries", len(entries))
+	}
+
+	if _, err := fileutil.SnapshotTree(root, filepath.Join(dir, "app-snap")); err != nil {
+		t.Errorf("Expected a sibling named like the tree to be accepted: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package fileutil
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotSymlink is returned, wrapped, by SwapDir if the
+// current path exists but is not a symbolic link
+var ErrNotSymlink = errors.New("not a symbolic link")
+
+// Swap records a switch made by SwapDir, so it can be undone
+type Swap struct {
+	Link     string // the symbolic link switched
+	Previous string // its target before, or "" if it did not exist
+	Next     string // its target now
+}
+
+// SwapDir atomically points the symbolic link current at the directory
+// next, creating the link if need be, as in deployments switching
+// between release directories: anything opening current sees either
+// the old or the new target, never neither. A relative next is taken
+// to be relative to the directory holding current, as for any link.
+func SwapDir(current, next string) (*Swap, error) {
+	target := next
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(current), next)
+	}
+	if fi, err := os.Stat(target); err != nil {
+		return nil, fmt.Errorf("SwapDir: %v", err)
+	} else if !fi.IsDir() {
+		return nil, fmt.Errorf("SwapDir: %s is not a directory", next)
+	}
+
+	previous, err := os.Readlink(current)
+	if err != nil && !os.IsNotExist(err) {
+		if _, lerr := os.Lstat(current); lerr == nil {
+			return nil, fmt.Errorf("SwapDir: %s: %w", current, ErrNotSymlink)
+		}
+		return nil, fmt.Errorf("SwapDir: %v", err)
+	}
+
+	if err := relink(current, next); err != nil {
+		return nil, fmt.Errorf("SwapDir: %v", err)
+	}
+	return &Swap{Link: current, Previous: previous, Next: next}, nil
+}
+
+// Rollback points the link back at its previous target,
+// or removes it if it did not exist before the swap
+func (s *Swap) Rollback() error {
+	if s.Previous == "" {
+		if err := os.Remove(s.Link); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Rollback: %v", err)
+		}
+		return nil
+	}
+
+	if err := relink(s.Link, s.Previous); err != nil {
+		return fmt.Errorf("Rollback: %v", err)
+	}
+	return nil
+}
+
+// relink replaces link with a symbolic link to target, by renaming
+// a new link over it, which is atomic
+func relink(link, target string) error {
+	// reserve a unique name next to the link for the new one
+	tmp, err := ioutil.TempFile(filepath.Dir(link), "."+filepath.Base(link)+".swap-")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+
+	if err := os.Symlink(target, tmp.Name()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), link); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
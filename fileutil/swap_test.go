@@ -0,0 +1,76 @@
+package fileutil_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/brinick/shell/fileutil"
+)
+
+func TestSwapDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges on Windows")
+	}
+
+	dir, err := ioutil.TempDir("", "swap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, release := range []string{"v1", "v2"} {
+		if err := os.MkdirAll(filepath.Join(dir, "releases", release), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "releases", release, "VERSION"), []byte(release), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	current := filepath.Join(dir, "current")
+	version := func() string {
+		data, _ := ioutil.ReadFile(filepath.Join(current, "VERSION"))
+		return string(data)
+	}
+
+	first, err := fileutil.SwapDir(current, "releases/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version() != "v1" || first.Previous != "" {
+		t.Fatalf("Expected the link to be created, got %q, %+v", version(), first)
+	}
+
+	second, err := fileutil.SwapDir(current, filepath.Join(dir, "releases", "v2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version() != "v2" || second.Previous != "releases/v1" {
+		t.Fatalf("Expected the link to be switched, got %q, %+v", version(), second)
+	}
+
+	if err := second.Rollback(); err != nil || version() != "v1" {
+		t.Errorf("Expected to be rolled back to v1, got %q, %v", version(), err)
+	}
+	if err := first.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(current); !os.IsNotExist(err) {
+		t.Errorf("Expected the link to be removed, got %v", err)
+	}
+
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected no temporary links to be left, got %d entries", len(entries))
+	}
+
+	if _, err := fileutil.SwapDir(current, "releases/v3"); err == nil {
+		t.Error("Expected a missing target to be refused")
+	}
+	if _, err := fileutil.SwapDir(filepath.Join(dir, "releases", "v1"), "v2"); !errors.Is(err, fileutil.ErrNotSymlink) {
+		t.Errorf("Expected a directory in place of the link to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package fileutil
+
+import "os"
+
+// ACLTag identifies whom an ACLEntry applies to
+type ACLTag uint16
+
+// The ACLEntry tags, as numbered by Linux
+const (
+	ACLUserObj  ACLTag = 0x01 // the file's owner
+	ACLUser     ACLTag = 0x02 // the user given by ID
+	ACLGroupObj ACLTag = 0x04 // the file's group
+	ACLGroup    ACLTag = 0x08 // the group given by ID
+	ACLMask     ACLTag = 0x10 // the most ACLUser, ACLGroupObj and ACLGroup entries grant
+	ACLOther    ACLTag = 0x20 // everyone else
+)
+
+// ACLEntry is one entry of a POSIX access control list
+type ACLEntry struct {
+	Tag  ACLTag
+	ID   int         // uid or gid for ACLUser and ACLGroup, else -1
+	Perm os.FileMode // read, write and execute bits, 0 to 7
+}
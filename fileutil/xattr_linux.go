@@ -0,0 +1,149 @@
+package fileutil
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// aclXattr is the attribute holding a file's access ACL
+const aclXattr = "system.posix_acl_access"
+
+// aclVersion is the version of the attribute's format
+const aclVersion = 2
+
+// GetXattr returns the value of the named extended attribute of
+// path, e.g. "user.origin". The error wraps syscall.ENODATA if
+// the attribute is not set.
+func GetXattr(path, name string) ([]byte, error) {
+	for {
+		size, err := syscall.Getxattr(path, name, nil)
+		if err != nil {
+			return nil, &os.PathError{Op: "getxattr", Path: path, Err: err}
+		}
+
+		value := make([]byte, size)
+		n, err := syscall.Getxattr(path, name, value)
+		if err == syscall.ERANGE {
+			// the value grew in between
+			continue
+		}
+		if err != nil {
+			return nil, &os.PathError{Op: "getxattr", Path: path, Err: err}
+		}
+		return value[:n], nil
+	}
+}
+
+// SetXattr sets the named extended attribute of path to value
+func SetXattr(path, name string, value []byte) error {
+	if err := syscall.Setxattr(path, name, value, 0); err != nil {
+		return &os.PathError{Op: "setxattr", Path: path, Err: err}
+	}
+	return nil
+}
+
+// RemoveXattr removes the named extended attribute of path
+func RemoveXattr(path, name string) error {
+	if err := syscall.Removexattr(path, name); err != nil {
+		return &os.PathError{Op: "removexattr", Path: path, Err: err}
+	}
+	return nil
+}
+
+// ListXattrs returns the names of the extended attributes of path
+// that can be read, which excludes e.g. those in the "trusted."
+// namespace unless running as root
+func ListXattrs(path string) ([]string, error) {
+	for {
+		size, err := syscall.Listxattr(path, nil)
+		if err != nil {
+			return nil, &os.PathError{Op: "listxattr", Path: path, Err: err}
+		}
+		if size == 0 {
+			return nil, nil
+		}
+
+		buf := make([]byte, size)
+		n, err := syscall.Listxattr(path, buf)
+		if err == syscall.ERANGE {
+			continue
+		}
+		if err != nil {
+			return nil, &os.PathError{Op: "listxattr", Path: path, Err: err}
+		}
+
+		// the names are each terminated by a NUL
+		return strings.Split(strings.TrimSuffix(string(buf[:n]), "\x00"), "\x00"), nil
+	}
+}
+
+// GetACL returns the POSIX access ACL of path, or nil
+// if it has none beyond its permission bits
+func GetACL(path string) ([]ACLEntry, error) {
+	value, err := GetXattr(path, aclXattr)
+	if pe, ok := err.(*os.PathError); ok && pe.Err == syscall.ENODATA {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(value) < 4 || (len(value)-4)%8 != 0 || binary.LittleEndian.Uint32(value) != aclVersion {
+		return nil, fmt.Errorf("GetACL %s: unrecognised ACL format", path)
+	}
+
+	var acl []ACLEntry
+	for b := value[4:]; len(b) > 0; b = b[8:] {
+		e := ACLEntry{
+			Tag:  ACLTag(binary.LittleEndian.Uint16(b)),
+			Perm: os.FileMode(binary.LittleEndian.Uint16(b[2:])),
+			ID:   -1,
+		}
+		if e.Tag == ACLUser || e.Tag == ACLGroup {
+			e.ID = int(binary.LittleEndian.Uint32(b[4:]))
+		}
+		acl = append(acl, e)
+	}
+	return acl, nil
+}
+
+// SetACL applies acl as the POSIX access ACL of path. It must have
+// exactly one of each of ACLUserObj, ACLGroupObj and ACLOther, and
+// an ACLMask if it has ACLUser or ACLGroup entries. The entries may
+// be given in any order.
+func SetACL(path string, acl []ACLEntry) error {
+	// the kernel only accepts entries sorted by tag, then by id
+	ids := make([]uint32, len(acl))
+	order := make([]int, len(acl))
+	for i, e := range acl {
+		ids[i] = 0xffffffff
+		if e.Tag == ACLUser || e.Tag == ACLGroup {
+			ids[i] = uint32(e.ID)
+		}
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if acl[a].Tag != acl[b].Tag {
+			return acl[a].Tag < acl[b].Tag
+		}
+		return ids[a] < ids[b]
+	})
+
+	value := make([]byte, 4, 4+8*len(acl))
+	binary.LittleEndian.PutUint32(value, aclVersion)
+	for _, i := range order {
+		e, id := acl[i], ids[i]
+
+		var b [8]byte
+		binary.LittleEndian.PutUint16(b[:], uint16(e.Tag))
+		binary.LittleEndian.PutUint16(b[2:], uint16(e.Perm&0o7))
+		binary.LittleEndian.PutUint32(b[4:], id)
+		value = append(value, b[:]...)
+	}
+	return SetXattr(path, aclXattr, value)
+}
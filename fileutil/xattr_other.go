@@ -0,0 +1,40 @@
+//go:build !linux
+// +build !linux
+
+package fileutil
+
+import (
+	"fmt"
+
+	"github.com/brinick/shell"
+)
+
+// GetXattr is only supported on Linux
+func GetXattr(path, name string) ([]byte, error) {
+	return nil, fmt.Errorf("GetXattr: %w", shell.ErrUnsupported)
+}
+
+// SetXattr is only supported on Linux
+func SetXattr(path, name string, value []byte) error {
+	return fmt.Errorf("SetXattr: %w", shell.ErrUnsupported)
+}
+
+// RemoveXattr is only supported on Linux
+func RemoveXattr(path, name string) error {
+	return fmt.Errorf("RemoveXattr: %w", shell.ErrUnsupported)
+}
+
+// ListXattrs is only supported on Linux
+func ListXattrs(path string) ([]string, error) {
+	return nil, fmt.Errorf("ListXattrs: %w", shell.ErrUnsupported)
+}
+
+// GetACL is only supported on Linux
+func GetACL(path string) ([]ACLEntry, error) {
+	return nil, fmt.Errorf("GetACL: %w", shell.ErrUnsupported)
+}
+
+// SetACL is only supported on Linux
+func SetACL(path string, acl []ACLEntry) error {
+	return fmt.Errorf("SetACL: %w", shell.ErrUnsupported)
+}
@@ -0,0 +1,134 @@
+//go:build linux
+// +build linux
+
+package fileutil_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"github.com/brinick/shell/fileutil"
+)
+
+// tempFile creates an empty file removed at the end of the test
+func tempFile(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "xattr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(path, nil, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestXattr(t *testing.T) {
+	path := tempFile(t)
+	if err := fileutil.SetXattr(path, "user.origin", []byte("backup")); err != nil {
+		t.Skipf("extended attributes not supported here: %v", err)
+	}
+	if err := fileutil.SetXattr(path, "user.empty", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := fileutil.GetXattr(path, "user.origin")
+	if err != nil || string(value) != "backup" {
+		t.Errorf("Expected \"backup\", got %q, %v", value, err)
+	}
+	if value, err := fileutil.GetXattr(path, "user.empty"); err != nil || len(value) != 0 {
+		t.Errorf("Expected an empty value, got %q, %v", value, err)
+	}
+
+	names, err := fileutil.ListXattrs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, name := range names {
+		found[name] = true
+	}
+	if !found["user.origin"] || !found["user.empty"] {
+		t.Errorf("Expected both attributes to be listed, got %q", names)
+	}
+
+	if err := fileutil.RemoveXattr(path, "user.origin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fileutil.GetXattr(path, "user.origin"); !errors.Is(err, syscall.ENODATA) {
+		t.Errorf("Expected ENODATA once removed, got %v", err)
+	}
+}
+
+func TestACL(t *testing.T) {
+	path := tempFile(t)
+
+	acl, err := fileutil.GetACL(path)
+	if err != nil || acl != nil {
+		t.Fatalf("Expected no ACL, got %v, %v", acl, err)
+	}
+
+	want := []fileutil.ACLEntry{
+		{Tag: fileutil.ACLUserObj, ID: -1, Perm: 6},
+		{Tag: fileutil.ACLUser, ID: 4321, Perm: 4},
+		{Tag: fileutil.ACLGroupObj, ID: -1, Perm: 4},
+		{Tag: fileutil.ACLMask, ID: -1, Perm: 4},
+		{Tag: fileutil.ACLOther, ID: -1, Perm: 0},
+	}
+	if err := fileutil.SetACL(path, want); err != nil {
+		t.Skipf("ACLs not supported here: %v", err)
+	}
+
+	acl, err = fileutil.GetACL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(acl, want) {
+		t.Errorf("Expected %v, got %v", want, acl)
+	}
+}
+
+func TestACLOrder(t *testing.T) {
+	path := tempFile(t)
+
+	acl := []fileutil.ACLEntry{
+		{Tag: fileutil.ACLOther, ID: -1, Perm: 0},
+		{Tag: fileutil.ACLGroup, ID: 200, Perm: 4},
+		{Tag: fileutil.ACLUser, ID: 4321, Perm: 4},
+		{Tag: fileutil.ACLMask, ID: -1, Perm: 6},
+		{Tag: fileutil.ACLUser, ID: 1234, Perm: 6},
+		{Tag: fileutil.ACLGroupObj, ID: -1, Perm: 4},
+		{Tag: fileutil.ACLUserObj, ID: -1, Perm: 6},
+	}
+	if err := fileutil.SetACL(path, acl); err != nil {
+		if errors.Is(err, syscall.EINVAL) {
+			t.Fatalf("Expected the entries to be put in order: %v", err)
+		}
+		t.Skipf("ACLs not supported here: %v", err)
+	}
+
+	got, err := fileutil.GetACL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []fileutil.ACLEntry{
+		{Tag: fileutil.ACLUserObj, ID: -1, Perm: 6},
+		{Tag: fileutil.ACLUser, ID: 1234, Perm: 6},
+		{Tag: fileutil.ACLUser, ID: 4321, Perm: 4},
+		{Tag: fileutil.ACLGroupObj, ID: -1, Perm: 4},
+		{Tag: fileutil.ACLGroup, ID: 200, Perm: 4},
+		{Tag: fileutil.ACLMask, ID: -1, Perm: 6},
+		{Tag: fileutil.ACLOther, ID: -1, Perm: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
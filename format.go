@@ -0,0 +1,59 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// stderrTail is the number of stderr lines included by %+v
+const stderrTail = 5
+
+// String summarises the command and its current state
+func (r *Result) String() string {
+	if r == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%q: %s", r.command, r.state())
+}
+
+// Format implements fmt.Formatter. The %+v verb adds the
+// last few lines of stderr to the summary given by String.
+func (r *Result) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		fmt.Fprint(f, r.String())
+		if f.Flag('+') && r != nil {
+			lines := r.stderrLines()
+			if len(lines) > stderrTail {
+				lines = lines[len(lines)-stderrTail:]
+			}
+			for _, line := range lines {
+				fmt.Fprintf(f, "\n  stderr: %s", line)
+			}
+		}
+	case 's':
+		fmt.Fprint(f, r.String())
+	case 'q':
+		fmt.Fprintf(f, "%q", r.String())
+	default:
+		fmt.Fprintf(f, "%%!%c(*shell.Result=%s)", verb, r.String())
+	}
+}
+
+// state describes where the command is at
+func (r *Result) state() string {
+	switch {
+	case r.Crashed():
+		return "crashed: " + r.CrashReason()
+	case r.TimedOut():
+		return fmt.Sprintf("timed out after %.2fs", r.Duration())
+	case r.Canceled():
+		return fmt.Sprintf("canceled after %.2fs", r.Duration())
+	case !r.IsReady() && r.status().StopTs == 0:
+		return fmt.Sprintf("running for %.2fs (pid %d)", r.Duration(), r.PID())
+	case r.Err() != nil:
+		return "failed: " + strings.TrimSpace(r.Err().Error())
+	default:
+		return fmt.Sprintf("exited with code %d after %.2fs", r.ExitCode(), r.Duration())
+	}
+}
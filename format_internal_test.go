@@ -0,0 +1,26 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestResultFormatting(t *testing.T) {
+	res := Run("echo first >&2; echo second >&2; exit 3")
+	<-res.Ready()
+
+	short := fmt.Sprintf("%v", res)
+	if !strings.HasPrefix(short, `"echo first >&2; echo second >&2; exit 3": exited with code 3 after`) {
+		t.Errorf("Unexpected summary: %s", short)
+	}
+
+	if strings.Contains(short, "stderr:") {
+		t.Errorf("Did not expect stderr in the short summary: %s", short)
+	}
+
+	long := fmt.Sprintf("%+v", res)
+	if !strings.HasSuffix(long, "\n  stderr: first\n  stderr: second") {
+		t.Errorf("Expected stderr tail in the long summary, got: %s", long)
+	}
+}
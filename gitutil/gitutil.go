@@ -0,0 +1,128 @@
+// Package gitutil provides helpers for common git operations,
+// run with the shell package.
+package gitutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/brinick/shell"
+)
+
+// ErrNotRepository is returned, wrapped in an Error, when
+// the directory is not within a git working tree
+var ErrNotRepository = errors.New("not a git repository")
+
+// Error describes a git invocation that failed
+type Error struct {
+	Args     []string
+	ExitCode int
+	Stderr   string
+	TimedOut bool
+}
+
+func (e *Error) Error() string {
+	cmd := "git " + strings.Join(e.Args, " ")
+	if e.TimedOut {
+		return cmd + ": timed out"
+	}
+	return fmt.Sprintf("%s: exit code %d: %s", cmd, e.ExitCode, e.Stderr)
+}
+
+// Unwrap returns ErrNotRepository if git reported the
+// directory was not in a repository, else nil
+func (e *Error) Unwrap() error {
+	if strings.Contains(e.Stderr, "not a git repository") {
+		return ErrNotRepository
+	}
+	return nil
+}
+
+// Clone clones the repository at url into dir. If progress is not
+// nil, it is called with each progress message as git prints it.
+// Options, such as shell.Timeout, are applied to the git command.
+func Clone(url, dir string, progress func(string), options ...shell.Option) error {
+	args := []string{"clone"}
+	if progress != nil {
+		args = append(args, "--progress")
+		options = append(options, shell.StderrWriter(&progressWriter{fn: progress}))
+	}
+
+	// the separator stops a url starting with "-" being taken as a flag
+	_, err := git(append(args, "--", url, dir), options)
+	return err
+}
+
+// progressWriter calls fn with each message written to it. Progress
+// updates overwrite one another with a carriage return, rather than
+// ending with a newline, so either ends a message.
+type progressWriter struct {
+	partial []byte
+	fn      func(string)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	data := append(w.partial, p...)
+	for {
+		i := bytes.IndexAny(data, "\r\n")
+		if i < 0 {
+			break
+		}
+		if msg := strings.TrimSpace(string(data[:i])); msg != "" {
+			w.fn(msg)
+		}
+		data = data[i+1:]
+	}
+	w.partial = append([]byte{}, data...)
+	return len(p), nil
+}
+
+// CurrentSHA returns the full SHA of the commit checked out in dir
+func CurrentSHA(dir string, options ...shell.Option) (string, error) {
+	res, err := git([]string{"-C", dir, "rev-parse", "HEAD"}, options)
+	if err != nil {
+		return "", err
+	}
+	return res.FullStdout().Text(), nil
+}
+
+// IsDirty indicates if the working tree in dir has changes, staged
+// or not, to tracked files, or any untracked files not ignored
+func IsDirty(dir string, options ...shell.Option) (bool, error) {
+	res, err := git([]string{"-C", dir, "status", "--porcelain"}, options)
+	if err != nil {
+		return false, err
+	}
+	return !res.FullStdout().Empty(), nil
+}
+
+// Checkout checks out ref, a branch, tag or commit, in dir
+func Checkout(dir, ref string, options ...shell.Option) error {
+	// a ref cannot start with "-", and would be taken as a flag
+	if strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("invalid ref %q", ref)
+	}
+
+	_, err := git([]string{"-C", dir, "checkout", "--quiet", ref, "--"}, options)
+	return err
+}
+
+// git runs git with the given arguments, returning an Error if it fails
+func git(args []string, options []shell.Option) (*shell.Result, error) {
+	res := shell.RunArgs("git", args, options...)
+	if res.IsError() && !res.TimedOut() {
+		return res, res.Err()
+	}
+
+	if res.TimedOut() || res.ExitCode() != 0 {
+		return res, &Error{
+			Args:     args,
+			ExitCode: res.ExitCode(),
+			Stderr:   res.FullStderr().Text(),
+			TimedOut: res.TimedOut(),
+		}
+	}
+	return res, nil
+}
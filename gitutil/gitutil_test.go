@@ -0,0 +1,125 @@
+package gitutil_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brinick/shell"
+	"github.com/brinick/shell/gitutil"
+)
+
+// newRepo creates a repository with two commits, the first tagged v1
+func newRepo(t *testing.T) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("repository is created with a POSIX shell script")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	dir, err := ioutil.TempDir("", "gitutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	script := `cd "$1" && git init -q . &&
+		git config user.email test@example.com && git config user.name test &&
+		echo one > file && git add file && git commit -qm one && git tag v1 &&
+		echo two > file && git commit -qam two`
+	if res := shell.RunWithArgs(script, []string{dir}); res.ExitCode() != 0 {
+		t.Fatalf("unable to create repository: %v", res)
+	}
+	return dir
+}
+
+func TestCloneAndCheckout(t *testing.T) {
+	origin := newRepo(t)
+	dir := filepath.Join(origin, "clone")
+
+	var progress []string
+	err := gitutil.Clone("file://"+origin, dir, func(msg string) {
+		progress = append(progress, msg)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(progress) == 0 || !strings.HasPrefix(progress[0], "Cloning into") {
+		t.Errorf("Unexpected progress: %q", progress)
+	}
+
+	// each update of a phase arrives as a message of its own
+	updates := 0
+	for _, msg := range progress {
+		if strings.Contains(msg, "%") && !strings.HasSuffix(msg, "done.") {
+			updates++
+		}
+	}
+	if updates < 2 {
+		t.Errorf("Expected intermediate progress updates, got %q", progress)
+	}
+
+	head, err := gitutil.CurrentSHA(dir)
+	if err != nil || len(head) != 40 {
+		t.Fatalf("Unexpected SHA %q: %v", head, err)
+	}
+
+	if err := gitutil.Checkout(dir, "v1"); err != nil {
+		t.Fatal(err)
+	}
+	if sha, _ := gitutil.CurrentSHA(dir); sha == head {
+		t.Error("Expected checkout to change the current commit")
+	}
+
+	var gerr *gitutil.Error
+	if err := gitutil.Checkout(dir, "no-such-ref"); !errors.As(err, &gerr) || gerr.ExitCode == 0 {
+		t.Errorf("Expected an Error for an unknown ref, got %v", err)
+	}
+}
+
+func TestIsDirty(t *testing.T) {
+	dir := newRepo(t)
+	if dirty, err := gitutil.IsDirty(dir); err != nil || dirty {
+		t.Fatalf("Expected a clean tree: %v %v", dirty, err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "new"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if dirty, err := gitutil.IsDirty(dir); err != nil || !dirty {
+		t.Errorf("Expected a dirty tree: %v %v", dirty, err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	dir, err := ioutil.TempDir("", "gitutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := gitutil.CurrentSHA(dir); !errors.Is(err, gitutil.ErrNotRepository) {
+		t.Errorf("Expected ErrNotRepository, got %v", err)
+	}
+
+	var gerr *gitutil.Error
+	err = gitutil.Clone("file:///no/such/repo", filepath.Join(dir, "x"), nil, shell.Timeout(time.Nanosecond))
+	if !errors.As(err, &gerr) {
+		t.Errorf("Expected an Error, got %v", err)
+	}
+
+	if err := gitutil.Checkout(dir, "--orphan=x"); err == nil || errors.As(err, &gerr) {
+		t.Errorf("Expected a ref starting with - to be refused before running git, got %v", err)
+	}
+}
@@ -1,8 +1,14 @@
 module github.com/brinick/shell
 
-go 1.14
+go 1.20
 
 require (
-	github.com/go-cmd/cmd v1.2.0
+	github.com/creack/pty v1.1.21
+	github.com/go-cmd/cmd v1.4.3
 	github.com/sirupsen/logrus v1.4.2
 )
+
+require (
+	github.com/konsorten/go-windows-terminal-sequences v1.0.1 // indirect
+	golang.org/x/sys v0.0.0-20190422165155-953cdadca894 // indirect
+)
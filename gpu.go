@@ -0,0 +1,131 @@
+package shell
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// gpuVisibilityVars are the environment variables
+// restricting the devices seen by each GPU runtime
+var gpuVisibilityVars = []string{
+	"CUDA_VISIBLE_DEVICES", // NVIDIA CUDA
+	"HIP_VISIBLE_DEVICES",  // AMD ROCm, HIP applications
+	"ROCR_VISIBLE_DEVICES", // AMD ROCm runtime
+}
+
+// GPUEnv is an Option to restrict the command to the GPUs with the
+// given indices, as reported by ProbeGPUs, for both the CUDA and
+// ROCm runtimes. With no devices, the command sees no GPUs at all.
+func GPUEnv(devices ...int) Option {
+	ids := make([]string, len(devices))
+	for i, d := range devices {
+		ids[i] = strconv.Itoa(d)
+	}
+
+	value := strings.Join(ids, ",")
+	if len(devices) == 0 {
+		// an empty list is treated by some runtimes as unset
+		value = "-1"
+	}
+
+	values := make([]string, len(gpuVisibilityVars))
+	for i, key := range gpuVisibilityVars {
+		values[i] = key + "=" + value
+	}
+	return Env(values)
+}
+
+// GPU describes an accelerator available on the host
+type GPU struct {
+	Index    int
+	Vendor   string // "nvidia" or "amd"
+	Name     string
+	MemoryMB int // 0 if unknown
+}
+
+// ProbeGPUs returns the GPUs available on the host, as reported by
+// nvidia-smi and rocm-smi. It returns no GPUs, and no error, if
+// neither tool is installed.
+func ProbeGPUs() ([]GPU, error) {
+	var gpus []GPU
+	if _, err := exec.LookPath("nvidia-smi"); err == nil {
+		res := RunArgs("nvidia-smi", []string{"--query-gpu=index,name,memory.total", "--format=csv,noheader,nounits"})
+		if err := checkSuccess(res); err != nil {
+			return nil, err
+		}
+		gpus = append(gpus, parseNvidiaSMI(res.FullStdout().Lines())...)
+	}
+
+	if _, err := exec.LookPath("rocm-smi"); err == nil {
+		res := RunArgs("rocm-smi", []string{"--showproductname", "--json"})
+		if err := checkSuccess(res); err != nil {
+			return nil, err
+		}
+		amd, err := parseROCmSMI(res.FullStdout().Text())
+		if err != nil {
+			return nil, err
+		}
+		gpus = append(gpus, amd...)
+	}
+	return gpus, nil
+}
+
+// checkSuccess returns an error describing a command that did not succeed
+func checkSuccess(r *Result) error {
+	if r.IsError() {
+		return r.Err()
+	}
+	if r.ExitCode() != 0 {
+		return fmt.Errorf("%s: exit code %d: %s", r.Command(), r.ExitCode(), r.FullStderr().Text())
+	}
+	return nil
+}
+
+// parseNvidiaSMI parses the CSV lines listing index, name and memory
+func parseNvidiaSMI(lines []string) []GPU {
+	var gpus []GPU
+	for _, line := range lines {
+		fields := strings.Split(line, ",")
+		if len(fields) != 3 {
+			continue
+		}
+
+		index, err := strconv.Atoi(strings.TrimSpace(fields[0]))
+		if err != nil {
+			continue
+		}
+		memory, _ := strconv.Atoi(strings.TrimSpace(fields[2]))
+		gpus = append(gpus, GPU{
+			Index:    index,
+			Vendor:   "nvidia",
+			Name:     strings.TrimSpace(fields[1]),
+			MemoryMB: memory,
+		})
+	}
+	return gpus
+}
+
+// parseROCmSMI parses the JSON product listing, keyed by "card<index>"
+func parseROCmSMI(text string) ([]GPU, error) {
+	var cards map[string]map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &cards); err != nil {
+		return nil, err
+	}
+
+	var gpus []GPU
+	for key, card := range cards {
+		index, err := strconv.Atoi(strings.TrimPrefix(key, "card"))
+		if err != nil || !strings.HasPrefix(key, "card") {
+			continue
+		}
+		name, _ := card["Card series"].(string)
+		gpus = append(gpus, GPU{Index: index, Vendor: "amd", Name: name})
+	}
+
+	sort.Slice(gpus, func(i, j int) bool { return gpus[i].Index < gpus[j].Index })
+	return gpus, nil
+}
@@ -0,0 +1,58 @@
+package shell
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestGPUEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses POSIX shell syntax")
+	}
+
+	res := Run("echo $CUDA_VISIBLE_DEVICES $HIP_VISIBLE_DEVICES $ROCR_VISIBLE_DEVICES", GPUEnv(0, 2))
+	if got := res.Stdout().Text(); got != "0,2 0,2 0,2" {
+		t.Errorf("Unexpected GPU environment: %q", got)
+	}
+
+	res = Run("echo $CUDA_VISIBLE_DEVICES", GPUEnv())
+	if got := res.Stdout().Text(); got != "-1" {
+		t.Errorf("Expected no visible GPUs, got %q", got)
+	}
+}
+
+func TestParseNvidiaSMI(t *testing.T) {
+	gpus := parseNvidiaSMI([]string{
+		"0, NVIDIA A100-SXM4-40GB, 40960",
+		"1, Tesla T4, 15360",
+		"garbage",
+	})
+
+	want := []GPU{
+		{Index: 0, Vendor: "nvidia", Name: "NVIDIA A100-SXM4-40GB", MemoryMB: 40960},
+		{Index: 1, Vendor: "nvidia", Name: "Tesla T4", MemoryMB: 15360},
+	}
+	if !reflect.DeepEqual(gpus, want) {
+		t.Errorf("Unexpected GPUs: %+v", gpus)
+	}
+}
+
+func TestParseROCmSMI(t *testing.T) {
+	gpus, err := parseROCmSMI(`{"card1": {"Card series": "MI250X"}, "card0": {"Card series": "MI210"}, "system": {"count": 2}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []GPU{
+		{Index: 0, Vendor: "amd", Name: "MI210"},
+		{Index: 1, Vendor: "amd", Name: "MI250X"},
+	}
+	if !reflect.DeepEqual(gpus, want) {
+		t.Errorf("Unexpected GPUs: %+v", gpus)
+	}
+
+	if _, err := parseROCmSMI("not json"); err == nil {
+		t.Error("Expected an error for invalid output")
+	}
+}
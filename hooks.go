@@ -0,0 +1,52 @@
+package shell
+
+// OnStart is an Option to call fn with the pid of the command once
+// it has been launched, from another goroutine. It is not called if
+// the command fails to launch. Multiple calls to this function will
+// be taken into account.
+func OnStart(fn func(pid int)) Option {
+	return func(s *command) {
+		s.onStart = append(s.onStart, fn)
+	}
+}
+
+// OnExit is an Option to call fn with the command's Result once it
+// is done, however it ended, including failing to launch. The Result
+// is ready by then, but fn may be called from another goroutine, so
+// may not yet have returned when a wait for the Result does.
+// Multiple calls to this function will be taken into account.
+func OnExit(fn func(*Result)) Option {
+	return func(s *command) {
+		s.onExit = append(s.onExit, fn)
+	}
+}
+
+// OnKill is an Option to call fn with the reason the command is
+// being stopped, "timed out", "canceled" or "stopped", before it is.
+// Multiple calls to this function will be taken into account.
+func OnKill(fn func(reason string)) Option {
+	return func(s *command) {
+		s.onKill = append(s.onKill, fn)
+	}
+}
+
+// started logs the launch and calls the OnStart callbacks once
+// the process is running, which happens asynchronously
+func (sc *command) started() {
+	defer close(sc.launched)
+	if !sc.awaitStart() {
+		return
+	}
+
+	sc.logStart()
+	for _, fn := range sc.onStart {
+		fn(sc.Result.PID())
+	}
+}
+
+// exited calls the OnExit callbacks
+func (sc *command) exited() {
+	for _, fn := range sc.onExit {
+		fn(sc.Result)
+	}
+}
@@ -0,0 +1,65 @@
+package shell
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	started := make(chan int, 1)
+	exited := make(chan *Result, 1)
+	killed := make(chan string, 1)
+
+	r := Run(
+		"sleep 5",
+		Timeout(100*time.Millisecond),
+		OnStart(func(pid int) { started <- pid }),
+		OnExit(func(r *Result) { exited <- r }),
+		OnKill(func(reason string) { killed <- reason }),
+	)
+
+	if pid := <-started; pid == 0 || pid != r.PID() {
+		t.Errorf("Expected OnStart with pid %d, got %d", r.PID(), pid)
+	}
+	if reason := <-killed; reason != "timed out" {
+		t.Errorf("Expected OnKill to be told of the timeout, got %q", reason)
+	}
+
+	select {
+	case res := <-exited:
+		if res != r || !res.IsReady() || !res.TimedOut() {
+			t.Errorf("Expected OnExit with the ready Result, got %v", res)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnExit was not called")
+	}
+}
+
+func TestLifecycleHooksNoKill(t *testing.T) {
+	exited := make(chan *Result, 1)
+	r := Run(
+		"true",
+		OnKill(func(string) { t.Error("Unexpected OnKill") }),
+		OnExit(func(r *Result) { exited <- r }),
+	)
+
+	if res := <-exited; res != r || res.ExitCode() != 0 {
+		t.Errorf("Expected OnExit with the Result, got %v", res)
+	}
+}
+
+func TestLifecycleHooksLaunchFailure(t *testing.T) {
+	boom := errors.New("boom")
+	exited := make(chan *Result, 1)
+	r := Run(
+		"true",
+		failWith(boom),
+		OnStart(func(int) { t.Error("Unexpected OnStart") }),
+		OnExit(func(r *Result) { exited <- r }),
+	)
+
+	if res := <-exited; res != r || !errors.Is(res.Err(), boom) {
+		t.Errorf("Expected OnExit with the failed Result, got %v", res)
+	}
+}
@@ -0,0 +1,176 @@
+package shell
+
+import (
+	"bytes"
+	"sync"
+)
+
+// MaxOutputLines is an Option to keep only the last n lines of each
+// of stdout and stderr in memory, rather than all the output. It can
+// be combined with MaxOutputBytes, but not with CompressOutput or PTY,
+// the last given of which takes effect.
+func MaxOutputLines(n int) Option {
+	return func(s *command) {
+		s.ringBuffers().maxLines = n
+	}
+}
+
+// MaxOutputBytes is an Option to keep only the last n bytes of
+// output, in whole lines, of each of stdout and stderr in memory,
+// rather than all the output. A line longer than n is cut short,
+// keeping its end. It can be combined with MaxOutputLines, but not
+// with CompressOutput or PTY, the last given of which takes effect.
+func MaxOutputBytes(n int) Option {
+	return func(s *command) {
+		s.ringBuffers().maxBytes = n
+	}
+}
+
+// OutputTruncated indicates if any output was dropped
+// to honour MaxOutputLines or MaxOutputBytes
+func (r *Result) OutputTruncated() bool {
+	for _, buf := range []lineBuffer{r.stdoutBuf, r.stderrBuf} {
+		if rb, ok := buf.(*ringBuffer); ok && rb.isTruncated() {
+			return true
+		}
+	}
+	return false
+}
+
+// ringBuffers installs bounded buffers for the command's output,
+// unless already installed, returning the limits to set
+func (sc *command) ringBuffers() *ringLimits {
+	if rb, ok := sc.Result.stdoutBuf.(*ringBuffer); ok {
+		return rb.limits
+	}
+
+	limits := &ringLimits{}
+	sc.Result.stdoutBuf = newRingBuffer(limits)
+	sc.Result.stderrBuf = newRingBuffer(limits)
+	return limits
+}
+
+// window returns the lines held for a stream, and the number of
+// earlier lines dropped to honour MaxOutputLines or MaxOutputBytes
+func window(buf lineBuffer, lines func() []string) ([]string, int) {
+	if rb, ok := buf.(*ringBuffer); ok {
+		return rb.window()
+	}
+	return lines(), 0
+}
+
+// unread returns those of the lines not yet returned,
+// counting those returned in seen
+func unread(lines []string, dropped int, seen *int) []string {
+	start := *seen - dropped
+	if start < 0 {
+		start = 0
+	}
+	*seen = dropped + len(lines)
+	return lines[start:]
+}
+
+// ------------------------------------------------------------------
+
+// ringLimits bounds the content of a ringBuffer, 0 meaning no limit
+type ringLimits struct {
+	maxLines int
+	maxBytes int
+}
+
+// ringBuffer is a lineBuffer holding only the latest lines
+type ringBuffer struct {
+	mu        sync.Mutex
+	limits    *ringLimits
+	lines     []string
+	size      int    // bytes held in lines, counting newlines
+	partial   []byte // the incomplete last line
+	dropped   int    // lines dropped
+	truncated bool   // any content was dropped
+	closed    bool
+}
+
+func newRingBuffer(limits *ringLimits) *ringBuffer {
+	return &ringBuffer{limits: limits}
+}
+
+func (b *ringBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data := p
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		line := append(b.partial, data[:i]...)
+		b.add(string(bytes.TrimSuffix(line, []byte("\r"))))
+		b.partial = b.partial[:0]
+		data = data[i+1:]
+	}
+	b.partial = append(b.partial, data...)
+
+	// a line can not be held beyond the byte limit
+	if max := b.limits.maxBytes; max > 0 && len(b.partial) > max {
+		b.partial = append(b.partial[:0], b.partial[len(b.partial)-max:]...)
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+// add appends a complete line, dropping the oldest to stay in bounds
+func (b *ringBuffer) add(line string) {
+	if max := b.limits.maxBytes; max > 0 && len(line)+1 > max {
+		line = line[len(line)+1-max:]
+		b.truncated = true
+	}
+
+	b.lines = append(b.lines, line)
+	b.size += len(line) + 1
+	for len(b.lines) > 0 && b.overLimit() {
+		b.size -= len(b.lines[0]) + 1
+		b.lines[0] = ""
+		b.lines = b.lines[1:]
+		b.dropped++
+		b.truncated = true
+	}
+}
+
+func (b *ringBuffer) overLimit() bool {
+	return (b.limits.maxLines > 0 && len(b.lines) > b.limits.maxLines) ||
+		(b.limits.maxBytes > 0 && b.size > b.limits.maxBytes)
+}
+
+// Close marks the content as complete, making
+// any incomplete last line a line of its own
+func (b *ringBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.partial) > 0 {
+		b.add(string(b.partial))
+		b.partial = nil
+	}
+	b.closed = true
+	return nil
+}
+
+// Lines returns the lines held
+func (b *ringBuffer) Lines() []string {
+	lines, _ := b.window()
+	return lines
+}
+
+// window returns the lines held, and the number dropped before them
+func (b *ringBuffer) window() ([]string, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string{}, b.lines...), b.dropped
+}
+
+func (b *ringBuffer) isTruncated() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.truncated
+}
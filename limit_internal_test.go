@@ -0,0 +1,81 @@
+package shell
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMaxOutputLines(t *testing.T) {
+	r := Run("seq 1 1000; echo err >&2; printf tail", MaxOutputLines(3))
+	if got := r.Stdout().Lines(); !reflect.DeepEqual(got, []string{"999", "1000", "tail"}) {
+		t.Errorf("Expected the last 3 lines, got %q", got)
+	}
+	if got := r.FullStderr().Lines(); !reflect.DeepEqual(got, []string{"err"}) {
+		t.Errorf("Expected stderr to be kept, got %q", got)
+	}
+	if !r.OutputTruncated() {
+		t.Error("Expected the output to be reported truncated")
+	}
+
+	if r := Run("seq 1 3", MaxOutputLines(3)); r.OutputTruncated() {
+		t.Error("Expected output within the limit not to be truncated")
+	}
+}
+
+func TestMaxOutputBytes(t *testing.T) {
+	r := Run("seq 1 1000; printf '%050d\\n' 0", MaxOutputBytes(20), MaxOutputLines(100))
+	if got := r.FullStdout().Lines(); len(got) != 1 || len(got[0]) != 19 {
+		t.Errorf("Expected the end of the long line, got %q", got)
+	}
+
+	r = Run("seq 1 1000", MaxOutputBytes(10))
+	if got := r.FullStdout().Lines(); !reflect.DeepEqual(got, []string{"999", "1000"}) {
+		t.Errorf("Expected the last 10 bytes of lines, got %q", got)
+	}
+}
+
+func TestRingBufferUnread(t *testing.T) {
+	buf := newRingBuffer(&ringLimits{maxLines: 2})
+	seen := 0
+	read := func() []string {
+		lines, dropped := buf.window()
+		return unread(lines, dropped, &seen)
+	}
+
+	buf.Write([]byte("a\nb\n"))
+	if got := read(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Expected a and b, got %q", got)
+	}
+
+	buf.Write([]byte("c\n"))
+	if got := read(); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("Expected only the new line, got %q", got)
+	}
+
+	buf.Write([]byte("d\ne\nf\n"))
+	if got := read(); !reflect.DeepEqual(got, []string{"e", "f"}) {
+		t.Errorf("Expected the lines still held, got %q", got)
+	}
+	if got := read(); len(got) != 0 {
+		t.Errorf("Expected nothing new, got %q", got)
+	}
+}
+
+func TestCRLFLines(t *testing.T) {
+	buffers := map[string][]Option{
+		"default":        nil,
+		"CompressOutput": {CompressOutput()},
+		"MaxOutputLines": {MaxOutputLines(10)},
+		"MaxOutputBytes": {MaxOutputBytes(1 << 10)},
+		"RawOutput":      {RawOutput()},
+	}
+	for name, options := range buffers {
+		r := Run(`printf 'a\r\nb\r\n'; printf 'e\r\n' >&2`, options...)
+		if got := r.FullStdout().Lines(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+			t.Errorf("%s: expected CRLF line endings to be stripped from stdout, got %q", name, got)
+		}
+		if got := r.FullStderr().Lines(); !reflect.DeepEqual(got, []string{"e"}) {
+			t.Errorf("%s: expected CRLF line endings to be stripped from stderr, got %q", name, got)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Logger receives the events of the commands run with the WithLogger
+// Option, each as a message and alternating keys and values.
+// A *slog.Logger satisfies it as is; use Logrus to adapt a logrus logger.
+type Logger interface {
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+}
+
+// WithLogger is an Option to log the command's launch, its end,
+// with its outcome, exit code and duration, and any attempt to kill it
+func WithLogger(l Logger) Option {
+	return func(s *command) {
+		s.logger = l
+	}
+}
+
+// logStart logs that the command has been launched
+func (sc *command) logStart() {
+	if sc.logger == nil {
+		return
+	}
+	sc.logger.Info("command started", sc.logFields("pid", sc.Result.PID())...)
+}
+
+// logDone logs how the command ended, as a warning if it failed
+func (sc *command) logDone() {
+	if sc.logger == nil {
+		return
+	}
+
+	r := sc.Result
+	fields := sc.logFields(
+		"outcome", exitClass(r),
+		"exit_code", r.ExitCode(),
+		"duration", time.Duration(r.Duration()*float64(time.Second)),
+	)
+	if err := r.Err(); err != nil {
+		fields = append(fields, "error", err)
+	}
+
+	if failed(r) {
+		sc.logger.Warn("command finished", fields...)
+		return
+	}
+	sc.logger.Info("command finished", fields...)
+}
+
+// logKill logs that the command is being stopped, and why
+func (sc *command) logKill() {
+	if sc.logger == nil {
+		return
+	}
+
+	sc.logger.Warn("command killed", sc.logFields("pid", sc.Result.PID(), "reason", sc.killReason())...)
+}
+
+// killReason describes why the command is being stopped
+func (sc *command) killReason() string {
+	switch {
+	case sc.Result.timedOut:
+		return "timed out"
+	case sc.Result.canceled:
+		return "canceled"
+	}
+	return "stopped"
+}
+
+// logFields prefixes keyvals with those identifying the command
+func (sc *command) logFields(keyvals ...interface{}) []interface{} {
+	fields := []interface{}{"command", redactCommand(sc.Result.command)}
+	if len(sc.Result.tags) > 0 {
+		fields = append(fields, "tags", strings.Join(sc.Result.tags, ","))
+	}
+	return append(fields, keyvals...)
+}
+
+// ------------------------------------------------------------------
+
+// Logrus adapts a logrus logger, or entry, to a Logger,
+// turning the keys and values into logrus fields
+func Logrus(l logrus.FieldLogger) Logger {
+	return logrusLogger{l}
+}
+
+type logrusLogger struct {
+	l logrus.FieldLogger
+}
+
+func (l logrusLogger) Info(msg string, keyvals ...interface{}) {
+	l.l.WithFields(logrusFields(keyvals)).Info(msg)
+}
+
+func (l logrusLogger) Warn(msg string, keyvals ...interface{}) {
+	l.l.WithFields(logrusFields(keyvals)).Warn(msg)
+}
+
+// logrusFields pairs up the keys and values
+func logrusFields(keyvals []interface{}) logrus.Fields {
+	fields := logrus.Fields{}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fields[fmt.Sprint(keyvals[i])] = keyvals[i+1]
+	}
+	return fields
+}
@@ -0,0 +1,9 @@
+//go:build go1.21
+// +build go1.21
+
+package shell
+
+import "log/slog"
+
+// a *slog.Logger is a Logger without any adapter
+var _ Logger = slog.Default()
@@ -0,0 +1,61 @@
+// Package mountutil mounts and unmounts filesystems with mount(8)
+// and umount(8), run with the shell package, retrying while the
+// target is busy. It is only supported on Linux.
+package mountutil
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Attempts is the number of times a busy mount or unmount is tried,
+// waiting Backoff after the first attempt, twice that after the
+// second, and so on
+var (
+	Attempts = 5
+	Backoff  = 100 * time.Millisecond
+)
+
+var (
+	// ErrBusy is returned, wrapped in an Error, if
+	// the target was still busy after all Attempts
+	ErrBusy = errors.New("target is busy")
+
+	// ErrNotMounted is returned, wrapped in an Error, if
+	// the operation did not leave the target as expected
+	ErrNotMounted = errors.New("not mounted")
+
+	// ErrMounted is returned, wrapped in an Error, if the
+	// target is still mounted after being unmounted
+	ErrMounted = errors.New("still mounted")
+)
+
+// Spec describes a filesystem to mount
+type Spec struct {
+	Source  string // device, directory or e.g. "tmpfs"
+	Target  string // mount point
+	FSType  string // e.g. "ext4", empty to let mount(8) guess
+	Options []string
+}
+
+// Error describes a mount or unmount that failed
+type Error struct {
+	Op       string // "mount" or "umount"
+	Target   string
+	ExitCode int
+	Stderr   string
+	Err      error // ErrBusy, ErrNotMounted or ErrMounted, if applicable
+}
+
+func (e *Error) Error() string {
+	if e.ExitCode == 0 && e.Err != nil {
+		return fmt.Sprintf("%s %s: %v", e.Op, e.Target, e.Err)
+	}
+	return fmt.Sprintf("%s %s: exit code %d: %s", e.Op, e.Target, e.ExitCode, e.Stderr)
+}
+
+// Unwrap returns the underlying cause, if known
+func (e *Error) Unwrap() error {
+	return e.Err
+}
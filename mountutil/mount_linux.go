@@ -0,0 +1,138 @@
+package mountutil
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brinick/shell"
+)
+
+// exitBusy is the exit code with which umount(8)
+// and mount(8) report a failure such as EBUSY
+const exitBusy = 32
+
+// Mount mounts the filesystem described by spec, then verifies
+// that the target is listed in /proc/mounts. Options, such as
+// shell.Timeout, are applied to each mount command.
+func Mount(spec Spec, options ...shell.Option) error {
+	var args []string
+	if spec.FSType != "" {
+		args = append(args, "-t", spec.FSType)
+	}
+	if len(spec.Options) > 0 {
+		args = append(args, "-o", strings.Join(spec.Options, ","))
+	}
+	args = append(args, "--", spec.Source, spec.Target)
+
+	if err := retry("mount", spec.Target, args, options); err != nil {
+		return err
+	}
+
+	if mounted, err := Mounted(spec.Target); err != nil {
+		return err
+	} else if !mounted {
+		return &Error{Op: "mount", Target: spec.Target, Err: ErrNotMounted}
+	}
+	return nil
+}
+
+// Unmount unmounts the filesystem at path, then verifies that it is
+// no longer listed in /proc/mounts. A lazy unmount detaches the
+// filesystem at once, completing once it is no longer busy.
+func Unmount(path string, lazy bool, options ...shell.Option) error {
+	var args []string
+	if lazy {
+		args = append(args, "--lazy")
+	}
+
+	if err := retry("umount", path, append(args, "--", path), options); err != nil {
+		return err
+	}
+
+	if mounted, err := Mounted(path); err != nil {
+		return err
+	} else if mounted {
+		return &Error{Op: "umount", Target: path, Err: ErrMounted}
+	}
+	return nil
+}
+
+// Mounted indicates if path is a mount point listed in /proc/mounts
+func Mounted(path string) (bool, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+
+	f, err := os.Open("/proc/mounts")
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 1 && unescape(fields[1]) == abs {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// retry runs the command, trying again with
+// increasing delays for as long as it is busy
+func retry(op, target string, args []string, options []shell.Option) error {
+	delay := Backoff
+	for attempt := 1; ; attempt++ {
+		res := shell.RunArgs(op, args, options...)
+		if res.IsError() && !res.TimedOut() {
+			return res.Err()
+		}
+
+		if !res.TimedOut() && res.ExitCode() == 0 {
+			return nil
+		}
+
+		stderr := res.FullStderr().Text()
+		busy := res.ExitCode() == exitBusy && strings.Contains(stderr, "busy")
+		if !busy || attempt >= Attempts {
+			err := &Error{Op: op, Target: target, ExitCode: res.ExitCode(), Stderr: stderr}
+			if busy {
+				err.Err = ErrBusy
+			}
+			return err
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// unescape decodes the octal escapes, e.g. \040 for
+// a space, used in the fields of /proc/mounts
+func unescape(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+3 < len(field) {
+			if n, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(field[i])
+	}
+	return b.String()
+}
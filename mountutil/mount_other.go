@@ -0,0 +1,25 @@
+//go:build !linux
+// +build !linux
+
+package mountutil
+
+import (
+	"fmt"
+
+	"github.com/brinick/shell"
+)
+
+// Mount is only supported on Linux
+func Mount(spec Spec, options ...shell.Option) error {
+	return fmt.Errorf("Mount: %w", shell.ErrUnsupported)
+}
+
+// Unmount is only supported on Linux
+func Unmount(path string, lazy bool, options ...shell.Option) error {
+	return fmt.Errorf("Unmount: %w", shell.ErrUnsupported)
+}
+
+// Mounted is only supported on Linux
+func Mounted(path string) (bool, error) {
+	return false, fmt.Errorf("Mounted: %w", shell.ErrUnsupported)
+}
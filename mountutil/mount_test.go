@@ -0,0 +1,81 @@
+//go:build linux
+// +build linux
+
+package mountutil_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brinick/shell/mountutil"
+)
+
+// mountPoint returns a directory to mount on, skipping the test
+// if the current user cannot mount filesystems
+func mountPoint(t *testing.T) string {
+	if os.Geteuid() != 0 {
+		t.Skip("mounting requires root")
+	}
+
+	dir, err := ioutil.TempDir("", "mount point")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestMountUnmount(t *testing.T) {
+	dir := mountPoint(t)
+
+	spec := mountutil.Spec{Source: "tmpfs", Target: dir, FSType: "tmpfs", Options: []string{"size=1m"}}
+	if err := mountutil.Mount(spec); err != nil {
+		t.Skipf("unable to mount: %v", err)
+	}
+
+	if mounted, err := mountutil.Mounted(dir); err != nil || !mounted {
+		t.Errorf("Expected %s to be mounted: %v", dir, err)
+	}
+
+	if err := mountutil.Unmount(dir, false); err != nil {
+		t.Fatal(err)
+	}
+	if mounted, _ := mountutil.Mounted(dir); mounted {
+		t.Errorf("Expected %s to be unmounted", dir)
+	}
+
+	var merr *mountutil.Error
+	if err := mountutil.Unmount(dir, false); !errors.As(err, &merr) || errors.Is(err, mountutil.ErrBusy) {
+		t.Errorf("Expected an Error unmounting a directory that is not mounted, got %v", err)
+	}
+}
+
+func TestUnmountBusy(t *testing.T) {
+	dir := mountPoint(t)
+	if err := mountutil.Mount(mountutil.Spec{Source: "tmpfs", Target: dir, FSType: "tmpfs"}); err != nil {
+		t.Skipf("unable to mount: %v", err)
+	}
+
+	f, err := os.Create(filepath.Join(dir, "open"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(attempts int, backoff time.Duration) {
+		mountutil.Attempts, mountutil.Backoff = attempts, backoff
+	}(mountutil.Attempts, mountutil.Backoff)
+	mountutil.Attempts, mountutil.Backoff = 2, time.Millisecond
+
+	if err := mountutil.Unmount(dir, false); !errors.Is(err, mountutil.ErrBusy) {
+		t.Errorf("Expected ErrBusy, got %v", err)
+	}
+
+	if err := mountutil.Unmount(dir, true); err != nil {
+		t.Errorf("Expected a lazy unmount to succeed: %v", err)
+	}
+	f.Close()
+}
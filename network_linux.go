@@ -0,0 +1,8 @@
+package shell
+
+// NoNetwork is an Option to run the command in a fresh network
+// namespace, leaving it with only an unconfigured loopback device.
+// It is shorthand for Sandboxed with only the Network layer.
+func NoNetwork() Option {
+	return Sandboxed(SandboxPolicy{Network: true})
+}
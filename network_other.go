@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package shell
+
+import "fmt"
+
+// NoNetwork is an Option to run the command without network access.
+// It is only supported on Linux; elsewhere the command fails
+// with an error wrapping ErrUnsupported.
+func NoNetwork() Option {
+	return func(s *command) {
+		s.err = fmt.Errorf("NoNetwork: %w", ErrUnsupported)
+	}
+}
@@ -0,0 +1,231 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// multiWriter duplicates writes to w, which may be nil, and
+// to each of the extra writers
+func multiWriter(w io.Writer, extra ...io.Writer) io.Writer {
+	if len(extra) == 0 {
+		return w
+	}
+
+	writers := extra
+	if w != nil {
+		writers = append([]io.Writer{w}, extra...)
+	}
+	return io.MultiWriter(writers...)
+}
+
+// ------------------------------------------------------------------
+
+// lineBuffer captures an output stream, to be read back as lines.
+// Close is called once the command is done.
+type lineBuffer interface {
+	io.WriteCloser
+	Lines() []string
+}
+
+// ------------------------------------------------------------------
+
+// memoryBuffer is a lineBuffer storing its content as written
+type memoryBuffer struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+}
+
+func newMemoryBuffer() *memoryBuffer {
+	return &memoryBuffer{}
+}
+
+func (b *memoryBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// Close marks the content as complete
+func (b *memoryBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+// Bytes returns a copy of the content written so far
+func (b *memoryBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte{}, b.buf.Bytes()...)
+}
+
+// Lines splits the content written so far. Until the
+// buffer is closed, a trailing incomplete line is left out.
+func (b *memoryBuffer) Lines() []string {
+	b.mu.Lock()
+	data := b.buf.String()
+	closed := b.closed
+	b.mu.Unlock()
+
+	lines := strings.SplitAfter(data, "\n")
+	if last := lines[len(lines)-1]; last == "" || !closed {
+		lines = lines[:len(lines)-1]
+	}
+	for i, line := range lines {
+		if strings.HasSuffix(line, "\n") {
+			lines[i] = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		}
+	}
+	return lines
+}
+
+// ------------------------------------------------------------------
+
+// discardBuffer is a lineBuffer keeping nothing. It
+// is never written to, the output going nowhere.
+type discardBuffer struct{}
+
+func (discardBuffer) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (discardBuffer) Close() error {
+	return nil
+}
+
+func (discardBuffer) Lines() []string {
+	return nil
+}
+
+// ------------------------------------------------------------------
+
+// compressedBuffer is a lineBuffer storing its content deflated
+type compressedBuffer struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	zw     *flate.Writer
+	closed bool
+}
+
+func newCompressedBuffer() *compressedBuffer {
+	b := &compressedBuffer{}
+	b.zw, _ = flate.NewWriter(&b.buf, flate.BestSpeed)
+	return b
+}
+
+func (b *compressedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.zw.Write(p)
+}
+
+// Close terminates the compressed stream
+func (b *compressedBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return b.zw.Close()
+}
+
+// Lines decompresses the content written so far. Until the
+// buffer is closed, a trailing incomplete line is left out.
+func (b *compressedBuffer) Lines() []string {
+	b.mu.Lock()
+	closed := b.closed
+	if !closed {
+		b.zw.Flush()
+	}
+	data := append([]byte{}, b.buf.Bytes()...)
+	b.mu.Unlock()
+
+	// a flushed, but unterminated, stream ends unexpectedly
+	content, _ := ioutil.ReadAll(flate.NewReader(bytes.NewReader(data)))
+
+	lines := []string{}
+	s := bufio.NewScanner(bytes.NewReader(content))
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	if !closed && len(lines) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// ------------------------------------------------------------------
+
+// lineWriter calls fn with each complete line written to it,
+// and with any trailing incomplete line once closed
+type lineWriter struct {
+	mu      sync.Mutex
+	partial []byte
+	fn      func(line string)
+}
+
+func newLineWriter(fn func(line string)) *lineWriter {
+	return &lineWriter{fn: fn}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data := append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		w.fn(string(bytes.TrimSuffix(data[:i], []byte("\r"))))
+		data = data[i+1:]
+	}
+	w.partial = append([]byte{}, data...)
+	return len(p), nil
+}
+
+// Close flushes any trailing incomplete line
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.partial) > 0 {
+		w.fn(string(w.partial))
+		w.partial = nil
+	}
+	return nil
+}
+
+// ------------------------------------------------------------------
+
+// crlfWriter converts the CRLF line endings written by a terminal
+// to LF, before passing the output on to w
+type crlfWriter struct {
+	w  io.Writer
+	cr bool // the last byte written was a CR, not yet passed on
+}
+
+func (c *crlfWriter) Write(p []byte) (int, error) {
+	out := make([]byte, 0, len(p)+1)
+	for _, b := range p {
+		if c.cr && b != '\n' {
+			out = append(out, '\r')
+		}
+		c.cr = b == '\r'
+		if !c.cr {
+			out = append(out, b)
+		}
+	}
+
+	if _, err := c.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
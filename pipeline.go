@@ -0,0 +1,136 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Pipeline is a sequence of commands, each reading
+// the output of the previous one on its stdin
+type Pipeline struct {
+	commands []string
+	pipefail bool
+}
+
+// Pipe creates the Pipeline running the commands in order, as with
+// "a | b | c" in the shell, but keeping the Result of each stage
+func Pipe(commands ...string) *Pipeline {
+	return &Pipeline{commands: commands}
+}
+
+// Pipefail makes the exit code of the pipeline that of the last stage
+// to exit with a non-zero code, rather than that of the last stage
+func (p *Pipeline) Pipefail() *Pipeline {
+	p.pipefail = true
+	return p
+}
+
+// Run launches all the stages, each configured with the Options, and
+// returns once they are all done. The stdout of every stage but the
+// last goes to the next stage, so is not captured in its Result. A
+// stage exiting early stops the previous one, as by SIGPIPE.
+func (p *Pipeline) Run(options ...Option) *PipelineResult {
+	res := &PipelineResult{pipefail: p.pipefail}
+	if len(p.commands) == 0 {
+		return res
+	}
+
+	var stdin *os.File
+	for i, command := range p.commands {
+		opts := append(append([]Option{}, options...), Bkgd())
+		if stdin != nil {
+			opts = append(opts, pipeIn(stdin))
+		}
+
+		if i < len(p.commands)-1 {
+			r, w, err := os.Pipe()
+			if err != nil {
+				stdin.Close()
+				res.Stages = append(res.Stages, Run(command, failWith(err)))
+				break
+			}
+			opts = append(opts, pipeOut(w))
+			stdin = r
+		}
+		res.Stages = append(res.Stages, Run(command, opts...))
+	}
+
+	for _, stage := range res.Stages {
+		<-stage.Ready()
+	}
+	return res
+}
+
+// pipeIn is an Option to read stdin from the pipe, closed once the command is done
+func pipeIn(r *os.File) Option {
+	return func(s *command) {
+		s.stdin = r
+		s.cleanups = append(s.cleanups, func() { r.Close() })
+	}
+}
+
+// pipeOut is an Option to write stdout to the pipe, closed once the command is done
+func pipeOut(w *os.File) Option {
+	return func(s *command) {
+		s.redirect = append(s.redirect, func(c *exec.Cmd) { c.Stdout = w })
+		s.cleanups = append(s.cleanups, func() { w.Close() })
+	}
+}
+
+// failWith is an Option failing the command with err
+func failWith(err error) Option {
+	return func(s *command) {
+		s.err = err
+	}
+}
+
+// ------------------------------------------------------------------
+
+// PipelineResult holds the Results of the stages of a Pipeline
+type PipelineResult struct {
+	Stages   []*Result
+	pipefail bool
+}
+
+// last returns the Result of the last stage, or nil if there are none
+func (r *PipelineResult) last() *Result {
+	if len(r.Stages) == 0 {
+		return nil
+	}
+	return r.Stages[len(r.Stages)-1]
+}
+
+// ExitCode returns the exit code of the last stage or, with
+// Pipefail, that of the last stage to exit with a non-zero code
+func (r *PipelineResult) ExitCode() int {
+	if r.pipefail {
+		for i := len(r.Stages) - 1; i >= 0; i-- {
+			if code := r.Stages[i].ExitCode(); code != 0 {
+				return code
+			}
+		}
+	}
+
+	if last := r.last(); last != nil {
+		return last.ExitCode()
+	}
+	return 0
+}
+
+// Err returns the first error from running any of the stages
+func (r *PipelineResult) Err() error {
+	for _, stage := range r.Stages {
+		if err := stage.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stdout returns the output of the last stage
+func (r *PipelineResult) Stdout() *Output {
+	if last := r.last(); last != nil {
+		return last.FullStdout()
+	}
+	return &Output{}
+}
@@ -0,0 +1,51 @@
+package shell
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPipe(t *testing.T) {
+	res := Pipe("printf 'b\na\nc\na\n'", "sort", "uniq -c", "sort -rn").Run()
+	if err := res.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := res.Stdout().Lines()
+	if len(lines) != 3 || lines[0] != "      2 a" {
+		t.Errorf("Unexpected pipeline output: %q", lines)
+	}
+
+	if len(res.Stages) != 4 {
+		t.Fatalf("Expected a Result per stage, got %d", len(res.Stages))
+	}
+	if !res.Stages[0].FullStdout().Empty() {
+		t.Error("Expected piped output not to be captured")
+	}
+}
+
+func TestPipefail(t *testing.T) {
+	res := Pipe("echo x; exit 3", "cat", "true").Run()
+	if res.ExitCode() != 0 {
+		t.Errorf("Expected the exit code of the last stage, got %d", res.ExitCode())
+	}
+
+	res = Pipe("echo x; exit 3", "cat; exit 4", "true").Pipefail().Run()
+	if res.ExitCode() != 4 {
+		t.Errorf("Expected the last non-zero exit code, got %d", res.ExitCode())
+	}
+	if code := res.Stages[0].ExitCode(); code != 3 {
+		t.Errorf("Expected each stage to keep its exit code, got %d", code)
+	}
+}
+
+func TestPipeEarlyExit(t *testing.T) {
+	start := time.Now()
+	res := Pipe("yes", "head -n 2").Run()
+	if got := res.Stdout().Text(); got != "y\ny" {
+		t.Errorf("Unexpected output: %q", got)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the first stage to be stopped, took %v", elapsed)
+	}
+}
@@ -0,0 +1,108 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrNotRun is the error of the Result of a command that was never
+// launched, as an earlier one failed
+var ErrNotRun = errors.New("not run: an earlier command failed")
+
+// Pool runs many commands concurrently, a bounded number at a time
+type Pool struct {
+	size     int
+	failFast bool
+}
+
+// NewPool creates a Pool running at most size commands at a time,
+// or one at a time if size is not positive
+func NewPool(size int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	return &Pool{size: size}
+}
+
+// FailFast makes the Pool cancel the commands running, and launch no
+// more, as soon as one fails. By default, all commands are run
+// regardless of failures.
+func (p *Pool) FailFast() *Pool {
+	p.failFast = true
+	return p
+}
+
+// Run executes the commands, each configured with the Options, and
+// returns once they are all done. Canceling the context cancels all
+// the commands. Commands are launched in order, and their Results
+// returned in the same order.
+func (p *Pool) Run(ctx context.Context, commands []string, options ...Option) *PoolResult {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	res := &PoolResult{Results: make([]*Result, len(commands))}
+	slots := make(chan struct{}, p.size)
+	var wg sync.WaitGroup
+	for i, command := range commands {
+		slots <- struct{}{}
+
+		if p.failFast && ctx.Err() != nil {
+			<-slots
+			res.Results[i] = Run(command, failWith(ErrNotRun))
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, command string) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			// the pool's context still applies should
+			// the Options give the command another one
+			opts := append([]Option{Context(ctx)}, options...)
+			opts = append(opts, alsoContext(ctx))
+			r := Run(command, opts...)
+			<-r.Ready()
+			res.Results[i] = r
+
+			if p.failFast && failed(r) {
+				cancel()
+			}
+		}(i, command)
+	}
+
+	wg.Wait()
+	return res
+}
+
+// ------------------------------------------------------------------
+
+// PoolResult holds the Results of the commands run by a Pool
+type PoolResult struct {
+	Results []*Result
+}
+
+// Failed returns the Results of the commands that failed,
+// or were not run
+func (r *PoolResult) Failed() []*Result {
+	var results []*Result
+	for _, res := range r.Results {
+		if failed(res) {
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
+// Err returns the first error from running any of the commands
+func (r *PoolResult) Err() error {
+	for _, res := range r.Results {
+		if err := res.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	commands := make([]string, 8)
+	for i := range commands {
+		commands[i] = "sleep 0.2; echo " + strconv.Itoa(i)
+	}
+	commands[3] = "exit 1"
+
+	start := time.Now()
+	res := NewPool(4).Run(context.Background(), commands)
+	elapsed := time.Since(start)
+
+	if elapsed > 1500*time.Millisecond {
+		t.Errorf("Expected commands to run concurrently, took %v", elapsed)
+	}
+	if elapsed < 350*time.Millisecond {
+		t.Errorf("Expected concurrency to be bounded, took %v", elapsed)
+	}
+
+	for i, r := range res.Results {
+		if i != 3 && r.Stdout().Text() != strconv.Itoa(i) {
+			t.Errorf("Result %d out of order: %q", i, r.FullStdout().Text())
+		}
+	}
+	if failed := res.Failed(); len(failed) != 1 || failed[0] != res.Results[3] {
+		t.Errorf("Expected only the fourth command to fail, got %v", failed)
+	}
+}
+
+func TestPoolFailFast(t *testing.T) {
+	commands := []string{"exit 1", "sleep 5", "echo never", "echo never"}
+	start := time.Now()
+	res := NewPool(2).FailFast().Run(context.Background(), commands)
+
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected running commands to be canceled, took %v", elapsed)
+	}
+	if len(res.Failed()) != len(commands) {
+		t.Errorf("Expected every command to fail or be skipped, got %v", res.Results)
+	}
+	if !errors.Is(res.Results[3].Err(), ErrNotRun) {
+		t.Errorf("Expected the last command not to run, got %v", res.Results[3])
+	}
+}
+
+func TestPoolContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	res := NewPool(2).Run(ctx, []string{"sleep 5", "sleep 5"})
+	for _, r := range res.Results {
+		if !r.TimedOut() {
+			t.Errorf("Expected the shared context to time out the commands: %v", r)
+		}
+	}
+}
+
+func TestPoolOptionPrecedence(t *testing.T) {
+	ctx := NewContext(context.Background(), Env([]string{"WHO=context"}))
+
+	res := NewPool(1).Run(ctx, []string{"echo $WHO"}, Env([]string{"WHO=explicit"}))
+	if got := res.Results[0].Stdout().Text(); got != "explicit" {
+		t.Errorf("Expected explicit Options to win over the context's, got %q", got)
+	}
+}
+
+func TestPoolFailFastWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	res := NewPool(2).FailFast().Run(context.Background(), []string{"exit 1", "sleep 5"}, Context(ctx))
+
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected the pool to cancel commands given their own context, took %v", elapsed)
+	}
+	if !res.Results[1].Canceled() {
+		t.Errorf("Expected the running command to be canceled: %v", res.Results[1])
+	}
+}
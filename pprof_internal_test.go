@@ -0,0 +1,31 @@
+package shell
+
+import (
+	"bytes"
+	"runtime/pprof"
+	"testing"
+	"time"
+)
+
+func TestProfileLabels(t *testing.T) {
+	stop := make(chan struct{})
+	res := Run("sleep 5", Bkgd(), Cancel(stop), Tag("nightly"))
+	defer func() {
+		close(stop)
+		<-res.Ready()
+	}()
+
+	for res.PID() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `"shell.command":"sleep", "shell.tag":"nightly"`
+	if !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Errorf("Expected goroutine profile to contain labels %s", want)
+	}
+}
@@ -0,0 +1,8 @@
+package shell
+
+// I/O scheduling classes for the IOPriority Option
+const (
+	IOClassRealtime   = 1
+	IOClassBestEffort = 2
+	IOClassIdle       = 3
+)
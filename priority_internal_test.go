@@ -0,0 +1,41 @@
+package shell
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestNiceOption(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("nice is not supported on Windows")
+	}
+
+	res := Run("nice", Nice(7))
+	if got := res.Stdout().Text(); got != "7" {
+		t.Errorf("Expected a niceness of 7, got %q %v", got, res.Stderr().Lines())
+	}
+
+	if !Run("true", Nice(42)).IsError() {
+		t.Error("Expected an error for an out of range level")
+	}
+}
+
+func TestIOPriorityOption(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("ionice is only supported on Linux")
+	}
+	if _, err := exec.LookPath("ionice"); err != nil {
+		t.Skip("ionice is not available")
+	}
+
+	res := Run("ionice", IOPriority(IOClassBestEffort, 6), Nice(3))
+	if got := res.Stdout().Text(); !strings.Contains(got, "best-effort") || !strings.Contains(got, "6") {
+		t.Errorf("Unexpected I/O priority: %q %v", got, res.Stderr().Lines())
+	}
+
+	if !Run("true", IOPriority(IOClassBestEffort, 8)).IsError() {
+		t.Error("Expected an error for an out of range level")
+	}
+}
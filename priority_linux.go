@@ -0,0 +1,36 @@
+package shell
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// IOPriority is an Option to run the command in the given I/O
+// scheduling class, one of IOClassRealtime, IOClassBestEffort or
+// IOClassIdle, at the given level from 0 (highest) to 7 (lowest).
+// The level is ignored for the idle class. It is applied with ionice(1).
+func IOPriority(class, level int) Option {
+	return func(s *command) {
+		if class < IOClassRealtime || class > IOClassIdle {
+			s.err = fmt.Errorf("IOPriority: unknown class %d", class)
+			return
+		}
+
+		if class == IOClassIdle {
+			s.prefix = append(s.prefix, "ionice", "-c", strconv.Itoa(class))
+			return
+		}
+
+		if level < 0 || level > 7 {
+			s.err = fmt.Errorf("IOPriority: level %d out of range [0, 7]", level)
+			return
+		}
+		s.prefix = append(s.prefix, "ionice", "-c", strconv.Itoa(class), "-n", strconv.Itoa(level))
+	}
+}
+
+// niceness converts the value returned by the getpriority system
+// call, which Linux offsets to be positive, to a niceness
+func niceness(prio int) int {
+	return 20 - prio
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package shell
+
+import "fmt"
+
+// IOPriority is an Option to run the command in the given I/O
+// scheduling class and level. It is only supported on Linux.
+func IOPriority(class, level int) Option {
+	return func(s *command) {
+		s.err = fmt.Errorf("IOPriority: %w", ErrUnsupported)
+	}
+}
+
+// niceness returns the value returned by getpriority,
+// which is the niceness itself beyond Linux
+func niceness(prio int) int {
+	return prio
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+// +build !windows
+
+package shell
+
+import (
+	"fmt"
+	"strconv"
+	"syscall"
+)
+
+// Nice is an Option to run the command with the given niceness,
+// from -20 (most favourable scheduling) to 19 (least favourable).
+// Only the superuser may lower the niceness below that of the
+// current process. It is applied with nice(1), adjusting the
+// niceness of the current process by the difference.
+func Nice(level int) Option {
+	return func(s *command) {
+		if level < -20 || level > 19 {
+			s.err = fmt.Errorf("Nice: level %d out of range [-20, 19]", level)
+			return
+		}
+
+		prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, 0)
+		if err != nil {
+			s.err = fmt.Errorf("Nice: %v", err)
+			return
+		}
+		s.prefix = append(s.prefix, "nice", "-n", strconv.Itoa(level-niceness(prio)))
+	}
+}
@@ -0,0 +1,11 @@
+package shell
+
+import "fmt"
+
+// Nice is an Option to run the command with the given niceness.
+// It is not supported on Windows.
+func Nice(level int) Option {
+	return func(s *command) {
+		s.err = fmt.Errorf("Nice: %w", ErrUnsupported)
+	}
+}
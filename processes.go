@@ -0,0 +1,73 @@
+package shell
+
+import (
+	"os"
+	"regexp"
+)
+
+// ProcessInfo describes a process running on the host
+type ProcessInfo struct {
+	PID     int
+	PPID    int
+	Name    string   // the executable name, as reported by the kernel
+	Cmdline []string // empty for kernel threads and zombies
+	RSS     int64    // resident memory, in bytes
+}
+
+// Processes returns the processes running on the host for which
+// filter returns true, or all of them if filter is nil. It is
+// only supported on Linux, where the process table is read from /proc.
+func Processes(filter func(ProcessInfo) bool) ([]ProcessInfo, error) {
+	procs, err := readProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	if filter == nil {
+		return procs, nil
+	}
+
+	var matched []ProcessInfo
+	for _, p := range procs {
+		if filter(p) {
+			matched = append(matched, p)
+		}
+	}
+	return matched, nil
+}
+
+// KillByName sends sig to every process, other than the current one,
+// whose name matches the regular expression pattern, and returns
+// those processes. With dryRun, the processes are returned but not
+// signalled. Processes that exit before being signalled are ignored.
+func KillByName(pattern string, sig os.Signal, dryRun bool) ([]ProcessInfo, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	self := os.Getpid()
+	procs, err := Processes(func(p ProcessInfo) bool {
+		return p.PID != self && re.MatchString(p.Name)
+	})
+	if err != nil || dryRun {
+		return procs, err
+	}
+
+	var signalled []ProcessInfo
+	for _, p := range procs {
+		proc, err := os.FindProcess(p.PID)
+		if err != nil {
+			continue
+		}
+
+		if err := proc.Signal(sig); err != nil {
+			if !alive(p.PID) {
+				continue
+			}
+			return signalled, err
+		}
+		signalled = append(signalled, p)
+	}
+	return signalled, nil
+}
@@ -0,0 +1,102 @@
+package shell
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestProcesses(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		if _, err := Processes(nil); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Expected ErrUnsupported, got %v", err)
+		}
+		return
+	}
+
+	procs, err := Processes(func(p ProcessInfo) bool { return p.PID == os.Getpid() })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(procs) != 1 {
+		t.Fatalf("Expected to find the current process, got %v", procs)
+	}
+	self := procs[0]
+	if self.PPID != os.Getppid() || self.RSS <= 0 || len(self.Cmdline) == 0 || self.Cmdline[0] != os.Args[0] {
+		t.Errorf("Unexpected process details: %+v", self)
+	}
+}
+
+func TestKillByName(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Processes is only supported on Linux")
+	}
+
+	// a uniquely named sleep, so that no other process is killed
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep is not available")
+	}
+	dir, err := ioutil.TempDir("", "processes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := "kbn" + strconv.Itoa(os.Getpid())
+	if err := os.Symlink(sleep, filepath.Join(dir, name)); err != nil {
+		t.Fatal(err)
+	}
+
+	res := RunArgs(filepath.Join(dir, name), []string{"5"}, Bkgd())
+	for res.PID() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	pattern := "^" + name + "$"
+	found, err := KillByName(pattern, syscall.SIGTERM, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasPID(found, res.PID()) {
+		t.Fatalf("Expected a dry run to find the sleep process, got %v", found)
+	}
+	if res.IsReady() {
+		t.Fatal("Expected a dry run not to signal the process")
+	}
+
+	killed, err := KillByName(pattern, syscall.SIGTERM, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasPID(killed, res.PID()) {
+		t.Errorf("Expected the sleep process to be signalled, got %v", killed)
+	}
+
+	select {
+	case <-res.Ready():
+	case <-time.After(2 * time.Second):
+		t.Error("Expected the process to be killed")
+	}
+
+	if _, err := KillByName("(", syscall.SIGTERM, true); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
+
+func hasPID(procs []ProcessInfo, pid int) bool {
+	for _, p := range procs {
+		if p.PID == pid {
+			return true
+		}
+	}
+	return false
+}
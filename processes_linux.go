@@ -0,0 +1,64 @@
+package shell
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// readProcesses reads the process table from /proc. Processes
+// exiting while it is read are left out.
+func readProcesses() ([]ProcessInfo, error) {
+	dirs, err := filepath.Glob("/proc/[0-9]*")
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := int64(os.Getpagesize())
+	procs := make([]ProcessInfo, 0, len(dirs))
+	for _, dir := range dirs {
+		pid, err := strconv.Atoi(filepath.Base(dir))
+		if err != nil {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "stat"))
+		if err != nil {
+			continue
+		}
+
+		// the name is in parentheses, and may itself contain
+		// spaces and parentheses, so parse around it
+		stat := string(data)
+		start, end := strings.Index(stat, "("), strings.LastIndex(stat, ")")
+		if start < 0 || end < start {
+			continue
+		}
+
+		// fields from the third, the process state, onwards
+		fields := strings.Fields(stat[end+1:])
+		if len(fields) < 22 {
+			continue
+		}
+
+		p := ProcessInfo{PID: pid, Name: stat[start+1 : end]}
+		p.PPID, _ = strconv.Atoi(fields[1])
+		pages, _ := strconv.ParseInt(fields[21], 10, 64)
+		p.RSS = pages * pageSize
+
+		if cmdline, err := ioutil.ReadFile(filepath.Join(dir, "cmdline")); err == nil && len(cmdline) > 0 {
+			p.Cmdline = strings.Split(strings.TrimSuffix(string(cmdline), "\x00"), "\x00")
+		}
+		procs = append(procs, p)
+	}
+	return procs, nil
+}
+
+// alive indicates if the process still exists
+func alive(pid int) bool {
+	_, err := os.Stat(fmt.Sprintf("/proc/%d", pid))
+	return err == nil
+}
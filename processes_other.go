@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package shell
+
+import "fmt"
+
+// readProcesses is not implemented beyond Linux
+func readProcesses() ([]ProcessInfo, error) {
+	return nil, fmt.Errorf("Processes: %w", ErrUnsupported)
+}
+
+// alive is never called, as no processes are ever found
+func alive(pid int) bool {
+	return false
+}
@@ -0,0 +1,42 @@
+package shell
+
+import (
+	"fmt"
+	"sync"
+)
+
+// profiles holds the registered Option bundles, by name
+var profiles = struct {
+	sync.RWMutex
+	options map[string][]Option
+}{options: map[string][]Option{}}
+
+// Profile registers the Options under the given name, for later use
+// with UseProfile, replacing any profile already registered under it.
+// Profiles are typically registered once, at program start up.
+func Profile(name string, options ...Option) {
+	profiles.Lock()
+	defer profiles.Unlock()
+	profiles.options[name] = append([]Option{}, options...)
+}
+
+// UseProfile is an Option applying the Options registered under the
+// given name with Profile. They are applied at this point, and so are
+// overridden by Options that come later. The command fails if no such
+// profile is registered.
+func UseProfile(name string) Option {
+	return func(s *command) {
+		profiles.RLock()
+		options, found := profiles.options[name]
+		profiles.RUnlock()
+
+		if !found {
+			s.err = fmt.Errorf("unknown profile %q", name)
+			return
+		}
+
+		for _, option := range options {
+			option(s)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package shell
+
+import "testing"
+
+func TestProfiles(t *testing.T) {
+	Profile("greeting", Env([]string{"GREETING=hello"}), Tag("greeting"))
+
+	res := Run(`echo "$GREETING"`, UseProfile("greeting"))
+	if got := res.Stdout().Text(); got != "hello" {
+		t.Errorf("Expected profile options to apply, got %q", got)
+	}
+
+	res = Run("true", UseProfile("no-such-profile"))
+	if !res.IsError() {
+		t.Error("Expected an unknown profile to be an error")
+	}
+}
@@ -0,0 +1,41 @@
+package shell
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+)
+
+func TestPTYOption(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		if res := Run("echo", PTY()); !errors.Is(res.Err(), ErrUnsupported) {
+			t.Errorf("Expected ErrUnsupported, got %v", res.Err())
+		}
+		return
+	}
+
+	res := Run("[ -t 0 ] && [ -t 1 ] && [ -t 2 ] && echo terminal; echo oops >&2; printf 'no newline'", PTY())
+	if res.IsError() {
+		t.Skipf("Unable to allocate a pseudo-terminal: %v", res.Err())
+	}
+
+	want := []string{"terminal", "oops", "no newline"}
+	got := res.Stdout().Lines()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %q, got %q", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Line %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+
+	if !res.Stderr().Empty() {
+		t.Errorf("Expected stderr to be captured as stdout, got %q", res.FullStderr().Lines())
+	}
+
+	res = Run("read line; echo \"got $line\"", PTY(), StdinString("typed\n"))
+	if lines := res.Stdout().Lines(); len(lines) == 0 || lines[len(lines)-1] != "got typed" {
+		t.Errorf("Expected the input to reach the command, got %q", lines)
+	}
+}
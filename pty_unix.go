@@ -0,0 +1,79 @@
+//go:build !windows
+// +build !windows
+
+package shell
+
+import (
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"syscall"
+
+	"github.com/creack/pty"
+)
+
+// PTY is an Option to run the command attached to a pseudo-terminal,
+// for tools that behave differently when not writing to a terminal,
+// e.g. to colour their output or draw progress bars. A terminal has a
+// single output stream, so everything the command writes is captured
+// as stdout. Input given with Stdin or Interactive is typed into the
+// terminal, and so is echoed back in the output.
+func PTY() Option {
+	return func(s *command) {
+		if s.Result.stdoutBuf == nil {
+			// output is copied from the terminal after the process is
+			// done, so is captured by the package rather than go-cmd
+			s.Result.stdoutBuf = newMemoryBuffer()
+			s.Result.stderrBuf = newMemoryBuffer()
+		}
+		s.setups = append(s.setups, s.openPTY)
+	}
+}
+
+// openPTY allocates the pseudo-terminal, to be
+// attached to the command as it is launched
+func (sc *command) openPTY() error {
+	master, tty, err := pty.Open()
+	if err != nil {
+		return err
+	}
+
+	started, copied := make(chan struct{}), make(chan struct{})
+	sc.terminal = func(c *exec.Cmd) {
+		close(started)
+		out := c.Stdout
+		if out == nil {
+			out = ioutil.Discard
+		}
+
+		if c.Stdin != nil {
+			in := c.Stdin
+			go io.Copy(master, in)
+		}
+
+		c.Stdin, c.Stdout, c.Stderr = tty, tty, tty
+		if c.SysProcAttr == nil {
+			c.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		c.SysProcAttr.Setpgid = false
+		c.SysProcAttr.Setsid = true
+		c.SysProcAttr.Setctty = true
+
+		go func() {
+			// reading stops with an error once no process holds the terminal
+			io.Copy(&crlfWriter{w: out}, master)
+			close(copied)
+		}()
+	}
+
+	sc.cleanups = append(sc.cleanups, func() {
+		tty.Close()
+		select {
+		case <-started:
+			<-copied
+		default:
+		}
+		master.Close()
+	})
+	return nil
+}
@@ -0,0 +1,11 @@
+package shell
+
+import "fmt"
+
+// PTY is an Option to run the command attached to a
+// pseudo-terminal. It is not supported on Windows.
+func PTY() Option {
+	return func(s *command) {
+		s.err = fmt.Errorf("PTY: %w", ErrUnsupported)
+	}
+}
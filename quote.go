@@ -0,0 +1,119 @@
+package shell
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// unquoted matches the words bash reads literally,
+// which need no quoting
+var unquoted = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// Quote escapes each of the args for bash, so that the shell reads
+// each as a single word with no expansion, and joins them with spaces
+func Quote(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if unquoted.MatchString(arg) {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// ------------------------------------------------------------------
+
+// CommandTemplate builds command strings from a text/template,
+// with every interpolated value quoted for bash
+type CommandTemplate struct {
+	tmpl *template.Template
+}
+
+// Template parses text, in the syntax of text/template, as a command
+// such as "git clone {{.URL}} {{.Dir}}". Each value the template
+// outputs is passed through Quote, so it lands in the command as a
+// single word whatever its content. A value that is a []string is
+// quoted as one word per element.
+func Template(text string) (*CommandTemplate, error) {
+	tmpl, err := template.New("command").Funcs(template.FuncMap{"shellquote": shellquote}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("Template: %v", err)
+	}
+
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			escapeNode(t.Tree, t.Tree.Root)
+		}
+	}
+	return &CommandTemplate{tmpl: tmpl}, nil
+}
+
+// Render executes the template with the given data,
+// returning the command string
+func (t *CommandTemplate) Render(data interface{}) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("Template: %v", err)
+	}
+	return b.String(), nil
+}
+
+// Run renders the template with the given data and runs the
+// resulting command. If rendering fails, the Result holds the error.
+func (t *CommandTemplate) Run(data interface{}, options ...Option) *Result {
+	command, err := t.Render(data)
+	if err != nil {
+		options = append(options, failWith(err))
+	}
+	return Run(command, options...)
+}
+
+// escapeNode makes every action under node that
+// outputs a value pipe it through shellquote
+func escapeNode(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			escapeNode(tree, child)
+		}
+	case *parse.ActionNode:
+		// actions declaring variables output nothing
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		quote := parse.NewIdentifier("shellquote").SetTree(tree).SetPos(n.Pos)
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{quote},
+		})
+	case *parse.IfNode:
+		escapeNode(tree, n.List)
+		escapeNode(tree, n.ElseList)
+	case *parse.RangeNode:
+		escapeNode(tree, n.List)
+		escapeNode(tree, n.ElseList)
+	case *parse.WithNode:
+		escapeNode(tree, n.List)
+		escapeNode(tree, n.ElseList)
+	}
+}
+
+// shellquote is the template function quoting an
+// action's value, the last of its args
+func shellquote(args ...interface{}) string {
+	if len(args) == 1 {
+		if words, ok := args[0].([]string); ok {
+			return Quote(words...)
+		}
+	}
+	return Quote(fmt.Sprint(args...))
+}
@@ -0,0 +1,87 @@
+package shell
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"ls", "-l", "/tmp/dir"}, "ls -l /tmp/dir"},
+		{[]string{""}, "''"},
+		{[]string{"a b"}, "'a b'"},
+		{[]string{"it's"}, `'it'\''s'`},
+		{[]string{"$(rm -rf ~)", "`id`", "a;b", "*"}, "'$(rm -rf ~)' '`id`' 'a;b' '*'"},
+	}
+
+	for _, tt := range tests {
+		if got := Quote(tt.args...); got != tt.want {
+			t.Errorf("Quote(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteRoundTrip(t *testing.T) {
+	args := []string{"plain", "", "with space", "it's", "$HOME", "a\nb", `back\slash`, "!"}
+	r := Run("printf '[%s]\\n' " + Quote(args...))
+	lines := r.Stdout().Lines()
+	want := []string{"[plain]", "[]", "[with space]", "[it's]", "[$HOME]", "[a", "b]", `[back\slash]`, "[!]"}
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %q, got %q", want, lines)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("Line %d: expected %q, got %q", i, want[i], lines[i])
+		}
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tmpl, err := Template(`git clone {{.URL}} {{.Dir}}{{range .Flags}} {{.}}{{end}}{{if .Depth}} --depth {{.Depth}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	command, err := tmpl.Render(map[string]interface{}{
+		"URL":   "https://example.com/repo.git",
+		"Dir":   "my dir; rm -rf ~",
+		"Flags": []string{"--quiet", "--branch=$x"},
+		"Depth": 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `git clone https://example.com/repo.git 'my dir; rm -rf ~' --quiet '--branch=$x' --depth 1`
+	if command != want {
+		t.Errorf("Expected %q, got %q", want, command)
+	}
+}
+
+func TestTemplateRun(t *testing.T) {
+	tmpl, err := Template(`printf '%s|' {{.}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := tmpl.Run([]string{"a b", "$c"}); r.Stdout().Text() != "a b|$c|" {
+		t.Errorf("Unexpected output: %q", r.FullStdout().Text())
+	}
+
+	if r := tmpl.Run(struct{}{}); r.Stdout().Text() != "{}|" {
+		t.Errorf("Unexpected output: %q", r.FullStdout().Text())
+	}
+
+	tmpl, _ = Template(`echo {{.Missing}}`)
+	if r := tmpl.Run(struct{}{}); r.Err() == nil || errors.Is(r.Err(), ErrNotRun) {
+		t.Errorf("Expected a rendering error, got %v", r.Err())
+	}
+
+	if _, err := Template(`echo {{`); err == nil {
+		t.Error("Expected a parse error")
+	}
+}
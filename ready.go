@@ -0,0 +1,95 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// readyPollInterval is the time between two checks
+// of whether a service is ready
+const readyPollInterval = 100 * time.Millisecond
+
+// ReadyCheck reports, with a nil error, that a service is ready
+type ReadyCheck func(ctx context.Context) error
+
+// ReadyTCP returns a ReadyCheck that succeeds once a TCP connection
+// can be made to addr, e.g. "localhost:8080" or ":8080"
+func ReadyTCP(addr string) ReadyCheck {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// ReadyHTTP returns a ReadyCheck that succeeds once a GET of url
+// returns the given status code
+func ReadyHTTP(url string, status int) ReadyCheck {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != status {
+			return fmt.Errorf("%s returned status %d, not %d", url, resp.StatusCode, status)
+		}
+		return nil
+	}
+}
+
+// WaitForPort waits until a TCP connection can be made to the port
+// on host, or until the context is done
+func WaitForPort(ctx context.Context, host string, port int) error {
+	return waitReady(ctx, nil, ReadyTCP(net.JoinHostPort(host, strconv.Itoa(port))))
+}
+
+// WaitForHTTP waits until a GET of url returns the given
+// status code, or until the context is done
+func WaitForHTTP(ctx context.Context, url string, status int) error {
+	return waitReady(ctx, nil, ReadyHTTP(url, status))
+}
+
+// WaitReady waits until the check succeeds, typically for a service
+// started by a command run in the background. It gives up once the
+// context is done, or if the command exits first.
+func (r *Result) WaitReady(ctx context.Context, check ReadyCheck) error {
+	return waitReady(ctx, r, check)
+}
+
+// waitReady polls the check until it succeeds, the context is done,
+// or the command, if any, is done
+func waitReady(ctx context.Context, r *Result, check ReadyCheck) error {
+	var exited <-chan struct{}
+	if r != nil {
+		exited = r.Ready()
+	}
+
+	for {
+		err := check(ctx)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("not ready: %w (last check: %v)", ctx.Err(), err)
+		case <-exited:
+			return fmt.Errorf("command exited with code %d before it was ready", r.ExitCode())
+		case <-time.After(readyPollInterval):
+		}
+	}
+}
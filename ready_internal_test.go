@@ -0,0 +1,79 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// listenLater starts listening on a free port after the delay,
+// returning the port
+func listenLater(t *testing.T, delay time.Duration) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	go func() {
+		time.Sleep(delay)
+		l, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(port))
+		if err != nil {
+			return
+		}
+		t.Cleanup(func() { l.Close() })
+
+		http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		}))
+	}()
+	return port
+}
+
+func TestWaitForPort(t *testing.T) {
+	port := listenLater(t, 200*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := WaitForPort(ctx, "127.0.0.1", port); err != nil {
+		t.Fatal(err)
+	}
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/health", port)
+	if err := WaitForHTTP(ctx, url, http.StatusAccepted); err != nil {
+		t.Error(err)
+	}
+
+	short, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if err := WaitForHTTP(short, url, http.StatusOK); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wrong status to time out, got %v", err)
+	}
+}
+
+func TestWaitReady(t *testing.T) {
+	port := listenLater(t, 200*time.Millisecond)
+	res := Run("sleep 5", Bkgd(), Timeout(5*time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := res.WaitReady(ctx, ReadyTCP("127.0.0.1:"+strconv.Itoa(port))); err != nil {
+		t.Error(err)
+	}
+	res.KillTree()
+
+	start := time.Now()
+	res = Run("exit 2", Bkgd())
+	if err := res.WaitReady(ctx, ReadyTCP("127.0.0.1:1")); err == nil {
+		t.Error("Expected an error for a command exiting before it is ready")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected to give up once the command exited, took %v", elapsed)
+	}
+}
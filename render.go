@@ -0,0 +1,114 @@
+package shell
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"text/template"
+)
+
+// RenderTemplateFile executes the text/template in the file src with
+// data, and writes the result to dst with permissions mode. Templates
+// can call "quote" to quote values for bash, as Quote does, e.g. in
+// scripts or environment files. A key missing from a map in data is an
+// error, rather than rendered as "<no value>". The file at dst is
+// replaced atomically, so it is never seen half written, and left as
+// is if rendering fails.
+//
+// If logger is not nil, the rendering is logged to it, with the
+// top-level values of data whose names look sensitive redacted.
+func RenderTemplateFile(src, dst string, data interface{}, mode os.FileMode, logger Logger) error {
+	tmpl, err := template.New(filepath.Base(src)).
+		Funcs(template.FuncMap{"quote": shellquote}).
+		Option("missingkey=error").
+		ParseFiles(src)
+	if err != nil {
+		return fmt.Errorf("RenderTemplateFile: %v", err)
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return fmt.Errorf("RenderTemplateFile: %v", err)
+	}
+
+	if err := writeAtomic(dst, b.Bytes(), mode); err != nil {
+		return fmt.Errorf("RenderTemplateFile: %v", err)
+	}
+
+	if logger != nil {
+		logger.Info("template rendered", "src", src, "dst", dst, "bytes", b.Len(), "data", redactData(data))
+	}
+	return nil
+}
+
+// writeAtomic writes data to a new file next to path, and
+// renames it over path once complete
+func writeAtomic(path string, data []byte, mode os.FileMode) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(mode)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// redactData returns the top-level values of a map or struct by name,
+// with those whose names look sensitive replaced by REDACTED, or
+// nil for any other data
+func redactData(data interface{}) map[string]interface{} {
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	values := map[string]interface{}{}
+	add := func(name string, value reflect.Value) {
+		if sensitiveKey.MatchString(name) {
+			values[name] = "REDACTED"
+		} else if value.CanInterface() {
+			values[name] = value.Interface()
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		for _, k := range v.MapKeys() {
+			add(k.String(), v.MapIndex(k))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				add(v.Type().Field(i).Name, v.Field(i))
+			}
+		}
+	default:
+		return nil
+	}
+	return values
+}
@@ -0,0 +1,93 @@
+package shell
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestRenderTemplateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "env.tmpl")
+	dst := filepath.Join(dir, "env.sh")
+	tmpl := "export NAME={{quote .Name}}\nexport TOKEN={{quote .Token}}\nexport ARGS=({{quote .Args}})\n"
+	if err := ioutil.WriteFile(src, []byte(tmpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(dst, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data := struct {
+		Name  string
+		Token string
+		Args  []string
+	}{"it's me", "s3cret", []string{"-v", "a b"}}
+
+	l := &recordingLogger{}
+	if err := RenderTemplateFile(src, dst, data, 0600, l); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "export NAME='it'\\''s me'\nexport TOKEN=s3cret\nexport ARGS=(-v 'a b')\n"
+	if got, _ := ioutil.ReadFile(dst); string(got) != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if fi, err := os.Stat(dst); err != nil || (runtime.GOOS != "windows" && fi.Mode().Perm() != 0600) {
+		t.Errorf("Expected the file to have mode 0600, got %v, %v", fi, err)
+	}
+
+	// the rendered file must be readable by the shell
+	r := Run(". " + Quote(dst) + ` && echo "$NAME|$TOKEN|${ARGS[1]}"`)
+	if got := r.Stdout().Text(); got != "it's me|s3cret|a b" {
+		t.Errorf("Unexpected values sourced: %q", got)
+	}
+
+	if len(l.info) != 1 {
+		t.Fatalf("Expected the rendering to be logged, got %q", l.info)
+	}
+	logged := l.fields[0]["data"].(map[string]interface{})
+	wantLogged := map[string]interface{}{"Name": "it's me", "Token": "REDACTED", "Args": []string{"-v", "a b"}}
+	if !reflect.DeepEqual(logged, wantLogged) {
+		t.Errorf("Expected %v to be logged, got %v", wantLogged, logged)
+	}
+}
+
+func TestRenderTemplateFileErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "bad.tmpl")
+	dst := filepath.Join(dir, "out")
+	if err := ioutil.WriteFile(src, []byte("{{.Missing.Field}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(dst, []byte("kept"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RenderTemplateFile(src, dst, map[string]int{}, 0644, nil); err == nil {
+		t.Error("Expected an execution error")
+	}
+	if got, _ := ioutil.ReadFile(dst); string(got) != "kept" {
+		t.Errorf("Expected the file to be left as is, got %q", got)
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("Expected no temporary files to be left, got %d entries", len(entries))
+	}
+
+	if err := RenderTemplateFile(filepath.Join(dir, "missing"), dst, nil, 0644, nil); err == nil {
+		t.Error("Expected a missing template to fail")
+	}
+}
@@ -0,0 +1,156 @@
+// Package report renders the Results of several shell commands
+// into human-readable reports.
+package report
+
+import (
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/brinick/shell"
+)
+
+// row is a single command's entry in the report
+type row struct {
+	Index    int
+	Command  string
+	Status   string
+	Failed   bool
+	ExitCode int
+	Duration float64
+	Percent  float64 // duration relative to the slowest command
+	Output   string
+}
+
+// HTML writes a standalone HTML report of the results: a table that
+// can be sorted by clicking its headers, each command's output in an
+// expandable section, and a bar showing its relative duration. Output
+// is taken from the transcript if the command was run with the
+// Interleave Option, else stdout is followed by stderr.
+func HTML(results []*shell.Result, w io.Writer) error {
+	rows := make([]row, len(results))
+
+	var slowest float64
+	for _, r := range results {
+		if r.Duration() > slowest {
+			slowest = r.Duration()
+		}
+	}
+
+	failures := 0
+	for i, r := range results {
+		failed := r.IsError() || r.TimedOut() || r.Canceled() || r.Crashed() || r.ExitCode() != 0
+		if failed {
+			failures++
+		}
+
+		rows[i] = row{
+			Index:    i + 1,
+			Command:  r.Command(),
+			Status:   status(r),
+			Failed:   failed,
+			ExitCode: r.ExitCode(),
+			Duration: r.Duration(),
+			Output:   output(r),
+		}
+		if slowest > 0 {
+			rows[i].Percent = 100 * r.Duration() / slowest
+		}
+	}
+
+	return page.Execute(w, struct {
+		Rows     []row
+		Failures int
+	}{rows, failures})
+}
+
+// status is a one-word description of how the command ended
+func status(r *shell.Result) string {
+	switch {
+	case !r.IsReady():
+		return "running"
+	case r.Crashed():
+		return "crashed"
+	case r.TimedOut():
+		return "timed out"
+	case r.Canceled():
+		return "canceled"
+	case r.IsError():
+		return "error"
+	case r.ExitCode() != 0:
+		return "failed"
+	default:
+		return "ok"
+	}
+}
+
+// output returns the command's output for display
+func output(r *shell.Result) string {
+	if t := r.Transcript(); t != "" {
+		return t
+	}
+
+	var b strings.Builder
+	for _, out := range []*shell.Output{r.FullStdout(), r.FullStderr()} {
+		for _, line := range out.Lines() {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+var page = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Command report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.4em; text-align: left; vertical-align: top; }
+th { cursor: pointer; background: #f4f4f4; }
+tr.failed td.status { color: #b00; font-weight: bold; }
+td.status { color: #070; }
+.bar { background: #69c; height: 0.8em; min-width: 1px; }
+pre { background: #f8f8f8; padding: 0.5em; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>Command report</h1>
+<p>{{len .Rows}} commands, {{.Failures}} failed.</p>
+<table id="results">
+<thead>
+<tr><th>#</th><th>Command</th><th>Status</th><th>Exit code</th><th>Duration (s)</th><th></th></tr>
+</thead>
+<tbody>
+{{range .Rows}}<tr{{if .Failed}} class="failed"{{end}}>
+<td>{{.Index}}</td>
+<td><details><summary><code>{{.Command}}</code></summary><pre>{{.Output}}</pre></details></td>
+<td class="status">{{.Status}}</td>
+<td>{{.ExitCode}}</td>
+<td>{{printf "%.3f" .Duration}}</td>
+<td style="width: 20%"><div class="bar" style="width: {{printf "%.1f" .Percent}}%"></div></td>
+</tr>
+{{end}}</tbody>
+</table>
+<script>
+document.querySelectorAll("#results th").forEach(function (th, col) {
+  var asc = true;
+  th.addEventListener("click", function () {
+    var body = document.querySelector("#results tbody");
+    var rows = Array.prototype.slice.call(body.rows);
+    rows.sort(function (a, b) {
+      var x = a.cells[col].innerText, y = b.cells[col].innerText;
+      var nx = parseFloat(x), ny = parseFloat(y);
+      var cmp = (isNaN(nx) || isNaN(ny)) ? x.localeCompare(y) : nx - ny;
+      return asc ? cmp : -cmp;
+    });
+    asc = !asc;
+    rows.forEach(function (row) { body.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`))
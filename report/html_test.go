@@ -0,0 +1,41 @@
+package report_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/brinick/shell"
+	"github.com/brinick/shell/report"
+)
+
+func TestHTML(t *testing.T) {
+	results := []*shell.Result{
+		shell.Run("echo '<b>bold</b>'"),
+		shell.Run("echo oops >&2; exit 3", shell.Interleave()),
+	}
+	for _, r := range results {
+		<-r.Ready()
+	}
+
+	var buf bytes.Buffer
+	if err := report.HTML(results, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	page := buf.String()
+	for _, want := range []string{
+		"2 commands, 1 failed.",
+		"&lt;b&gt;bold&lt;/b&gt;",
+		`<tr class="failed">`,
+		"[err]",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected report to contain %q", want)
+		}
+	}
+
+	if strings.Contains(page, "<b>bold</b>") {
+		t.Error("Expected command output to be escaped")
+	}
+}
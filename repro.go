@@ -0,0 +1,141 @@
+package shell
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// sensitiveKey matches the names of environment
+// variables whose values are left out of repro bundles
+var sensitiveKey = regexp.MustCompile(`(?i)secret|token|passw|key|credential|auth|cookie|session`)
+
+// reproFile is a file in a repro bundle
+type reproFile struct {
+	name    string
+	content string
+}
+
+// ReproBundle writes a gzipped tarball collecting what is needed to
+// reproduce the command, typically one that failed, for a bug report:
+// the command and its outcome, its environment, with the values of
+// sensitive looking variables redacted there and in the command, a listing of the working
+// directory, its output, and details of the host.
+func (r *Result) ReproBundle(w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+
+	files := []reproFile{
+		{"command.txt", r.reproSummary()},
+		{"env.txt", r.reproEnv()},
+		{"cwd.txt", reproListing()},
+		{"stdout.txt", joinLines(r.stdoutLines())},
+		{"stderr.txt", joinLines(r.stderrLines())},
+		{"host.txt", reproHost()},
+	}
+	if r.transcript != nil {
+		files = append(files, reproFile{"transcript.txt", r.Transcript()})
+	}
+
+	now := time.Now()
+	for _, f := range files {
+		hdr := &tar.Header{
+			Name:    f.name,
+			Mode:    0644,
+			Size:    int64(len(f.content)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(tw, f.content); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// reproSummary describes the command and its outcome
+func (r *Result) reproSummary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "command: %s\n", redactCommand(r.command))
+	fmt.Fprintf(&b, "state: %s\n", r.state())
+	fmt.Fprintf(&b, "exit code: %d\n", r.ExitCode())
+	if len(r.tags) > 0 {
+		fmt.Fprintf(&b, "tags: %s\n", strings.Join(r.tags, ", "))
+	}
+	if st := r.status(); st.StartTs > 0 {
+		fmt.Fprintf(&b, "started: %s\n", time.Unix(0, st.StartTs).Format(time.RFC3339Nano))
+	}
+	fmt.Fprintf(&b, "duration: %.3fs\n", r.Duration())
+	return b.String()
+}
+
+// reproEnv lists the command's environment, redacting sensitive values
+func (r *Result) reproEnv() string {
+	env := r.env
+	var b strings.Builder
+	if env == nil {
+		b.WriteString("# inherited from the calling process\n")
+		env = os.Environ()
+	}
+
+	for _, kv := range env {
+		i := strings.Index(kv, "=")
+		if i > 0 && sensitiveKey.MatchString(kv[:i]) {
+			kv = kv[:i] + "=REDACTED"
+		}
+		b.WriteString(kv + "\n")
+	}
+	return b.String()
+}
+
+// reproListing lists the working directory, in which the command ran
+func reproListing() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Sprintf("unable to get working directory: %v\n", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", dir)
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		fmt.Fprintf(&b, "unable to list: %v\n", err)
+	}
+	for _, fi := range infos {
+		fmt.Fprintf(&b, "%s %10d %s %s\n", fi.Mode(), fi.Size(), fi.ModTime().Format(time.RFC3339), fi.Name())
+	}
+	return b.String()
+}
+
+// reproHost describes the host the command ran on
+func reproHost() string {
+	var b strings.Builder
+	hostname, _ := os.Hostname()
+	fmt.Fprintf(&b, "hostname: %s\n", hostname)
+	fmt.Fprintf(&b, "os/arch: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "cpus: %d\n", runtime.NumCPU())
+	fmt.Fprintf(&b, "go: %s\n", runtime.Version())
+	fmt.Fprintf(&b, "uid: %d\n", os.Getuid())
+	return b.String()
+}
+
+// joinLines joins output lines, each terminated by a newline
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
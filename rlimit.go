@@ -0,0 +1,16 @@
+package shell
+
+// RlimitInfinity is the Rlimit value for no limit
+const RlimitInfinity = ^uint64(0)
+
+// LimitExceeded indicates if the command was killed for exceeding
+// a limit set with the Rlimit Option: the CPU time limit (RLIMIT_CPU)
+// or the file size limit (RLIMIT_FSIZE). Exceeding other limits
+// makes system calls fail rather than the process be killed, and so
+// surfaces in the command's own output and exit code.
+func (r *Result) LimitExceeded() bool {
+	if len(r.rlimits) == 0 || !r.IsReady() || r.timedOut || r.canceled {
+		return false
+	}
+	return killedByLimit(r)
+}
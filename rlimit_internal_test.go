@@ -0,0 +1,40 @@
+//go:build linux
+// +build linux
+
+package shell
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+)
+
+func TestRlimitOption(t *testing.T) {
+	if _, err := exec.LookPath("prlimit"); err != nil {
+		t.Skip("prlimit is not available")
+	}
+
+	res := Run("ulimit -Sn; ulimit -Hn", Rlimit(syscall.RLIMIT_NOFILE, 64, 128))
+	if got := res.Stdout().Lines(); len(got) != 2 || got[0] != "64" || got[1] != "128" {
+		t.Errorf("Unexpected limits: %v %v", got, res.Stderr().Lines())
+	}
+	if res.LimitExceeded() {
+		t.Error("Expected no limit to be exceeded")
+	}
+
+	res = Run("while :; do :; done", Rlimit(syscall.RLIMIT_CPU, 1, 2))
+	if !res.LimitExceeded() {
+		t.Errorf("Expected the CPU limit to be exceeded: %v", res)
+	}
+
+	// killed, but well within the CPU time limit
+	for _, command := range []string{"kill -KILL $$", "exit 137", "kill -XCPU $$"} {
+		if res := Run(command, Rlimit(syscall.RLIMIT_CPU, 10, 20)); res.LimitExceeded() {
+			t.Errorf("%s: expected the CPU limit not to be exceeded", command)
+		}
+	}
+
+	if !Run("true", Rlimit(syscall.RLIMIT_CPU, 2, 1)).IsError() {
+		t.Error("Expected an error for a soft limit above the hard limit")
+	}
+}
@@ -0,0 +1,107 @@
+package shell
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/go-cmd/cmd"
+)
+
+// rlimitFlags maps each resource to the prlimit(1) flag setting it
+var rlimitFlags = map[int]string{
+	syscall.RLIMIT_AS:     "--as",
+	syscall.RLIMIT_CORE:   "--core",
+	syscall.RLIMIT_CPU:    "--cpu",
+	syscall.RLIMIT_DATA:   "--data",
+	syscall.RLIMIT_FSIZE:  "--fsize",
+	syscall.RLIMIT_NOFILE: "--nofile",
+	syscall.RLIMIT_STACK:  "--stack",
+}
+
+// Rlimit is an Option to set the soft and hard limits of the given
+// resource, one of the syscall.RLIMIT_* values, for the command.
+// Use RlimitInfinity for no limit. Multiple calls to this function
+// will be taken into account. It is applied with prlimit(1).
+func Rlimit(resource int, soft, hard uint64) Option {
+	return func(s *command) {
+		flag, ok := rlimitFlags[resource]
+		if !ok {
+			s.err = fmt.Errorf("Rlimit: unknown resource %d", resource)
+			return
+		}
+
+		if soft > hard {
+			s.err = fmt.Errorf("Rlimit: soft limit %d above hard limit %d", soft, hard)
+			return
+		}
+
+		s.prefix = append(s.prefix, "prlimit", flag+"="+rlimitValue(soft)+":"+rlimitValue(hard), "--")
+		s.Result.rlimits = append(s.Result.rlimits, resource)
+
+		// the CPU time used tells if the limit killed the process,
+		// as the signals it sends could come from anywhere
+		if resource == syscall.RLIMIT_CPU && soft != RlimitInfinity {
+			s.Result.cpuLimit = time.Duration(soft) * time.Second
+			s.beforeExec = append(s.beforeExec, func(c *exec.Cmd) {
+				s.Result.process = c
+			})
+		}
+	}
+}
+
+// cpuTimeSlack allows for the CPU time reported for a process
+// falling slightly short of the limit it was stopped at
+const cpuTimeSlack = 100 * time.Millisecond
+
+// rlimitValue formats the limit as understood by prlimit
+func rlimitValue(v uint64) string {
+	if v == RlimitInfinity {
+		return "unlimited"
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+// killedByLimit indicates if the process died from a signal raised
+// by reaching one of the limited resources. The signal is either
+// reported directly, or via the exit code of the shell running it.
+// The CPU time limit is only taken to be reached if the process used
+// about as much CPU time as its soft limit, or more.
+func killedByLimit(r *Result) bool {
+	status := r.status()
+	for _, resource := range r.rlimits {
+		switch resource {
+		case syscall.RLIMIT_CPU:
+			// the hard limit is enforced with SIGKILL
+			if killedBy(status, syscall.SIGXCPU) || killedBy(status, syscall.SIGKILL) {
+				if r.cpuLimit > 0 && cpuTime(r.process) >= r.cpuLimit-cpuTimeSlack {
+					return true
+				}
+			}
+		case syscall.RLIMIT_FSIZE:
+			if killedBy(status, syscall.SIGXFSZ) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// killedBy indicates if the process died from the signal
+func killedBy(status *cmd.Status, sig syscall.Signal) bool {
+	if status.Exit == 128+int(sig) {
+		return true
+	}
+	return status.Error != nil && status.Error.Error() == "signal: "+sig.String()
+}
+
+// cpuTime returns the user and system CPU time used
+// by the process, once it is done, or else zero
+func cpuTime(c *exec.Cmd) time.Duration {
+	if c == nil || c.ProcessState == nil {
+		return 0
+	}
+	return c.ProcessState.UserTime() + c.ProcessState.SystemTime()
+}
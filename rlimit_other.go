@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package shell
+
+import "fmt"
+
+// Rlimit is an Option to set the soft and hard limits of the
+// given resource for the command. It is only supported on Linux.
+func Rlimit(resource int, soft, hard uint64) Option {
+	return func(s *command) {
+		s.err = fmt.Errorf("Rlimit: %w", ErrUnsupported)
+	}
+}
+
+// killedByLimit is never true, as limits cannot be set
+func killedByLimit(r *Result) bool {
+	return false
+}
@@ -0,0 +1,170 @@
+// Package rsyncutil runs rsync with the shell package, reporting
+// the changes it makes as structured entries.
+package rsyncutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/brinick/shell"
+)
+
+// outFormat makes rsync print the itemized changes, and the target
+// of symlinks, for each file it touches
+const outFormat = "--out-format=%i %n%L"
+
+// RsyncOptions configures the rsync invocation
+type RsyncOptions struct {
+	Archive  bool     // -a: recurse, preserving permissions, times, links...
+	Delete   bool     // --delete files in dst absent from src
+	DryRun   bool     // -n: report the changes without making them
+	Compress bool     // -z: compress data in transit
+	Exclude  []string // --exclude patterns
+	Args     []string // any further arguments, placed before src and dst
+
+	// Progress, if not nil, is called with each change as rsync makes it
+	Progress func(Entry)
+
+	// Path is the rsync executable, by default "rsync" from PATH
+	Path string
+
+	// Options are applied to the rsync command, e.g. shell.Timeout
+	Options []shell.Option
+}
+
+// Entry is a single change made by rsync, as itemized by it
+type Entry struct {
+	// Update is the kind of update: '<' sent, '>' received,
+	// 'c' created locally, 'h' hard link, '.' attributes only,
+	// '*' a message, e.g. for deletions
+	Update byte
+
+	// Type is the file type: 'f' file, 'd' directory,
+	// 'L' symlink, 'D' device, 'S' special file
+	Type byte
+
+	// Attributes are the itemized attribute changes, e.g. "c.t......"
+	// or "+++++++++" for a new file
+	Attributes string
+
+	Path    string
+	Target  string // of a symlink
+	Deleted bool
+}
+
+// New indicates if the entry is a file created by the sync
+func (e Entry) New() bool {
+	return strings.Trim(e.Attributes, "+") == "" && e.Attributes != ""
+}
+
+// SyncReport describes the outcome of an Rsync call
+type SyncReport struct {
+	Entries []Entry
+
+	// Result is that of the rsync command,
+	// e.g. to include it in an HTML report
+	Result *shell.Result
+}
+
+// Error describes an rsync invocation that failed
+type Error struct {
+	Args     []string
+	ExitCode int
+	Stderr   string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rsync %s: exit code %d: %s", strings.Join(e.Args, " "), e.ExitCode, e.Stderr)
+}
+
+// Rsync synchronises dst with src. Both are passed to rsync as single
+// arguments, never interpreted by a shell, so may come from user input.
+// The trailing slash convention of rsync applies to src.
+func Rsync(src, dst string, opts RsyncOptions) (*SyncReport, error) {
+	args := opts.args()
+	args = append(args, "--", src, dst)
+
+	report := &SyncReport{}
+	options := append(append([]shell.Option{}, opts.Options...), shell.OnStdoutLine(func(line string) {
+		entry, ok := ParseItemized(line)
+		if !ok {
+			return
+		}
+		report.Entries = append(report.Entries, entry)
+		if opts.Progress != nil {
+			opts.Progress(entry)
+		}
+	}))
+
+	path := opts.Path
+	if path == "" {
+		path = "rsync"
+	}
+
+	report.Result = shell.RunArgs(path, args, options...)
+	if report.Result.IsError() && !report.Result.TimedOut() {
+		return report, report.Result.Err()
+	}
+
+	if report.Result.TimedOut() || report.Result.ExitCode() != 0 {
+		return report, &Error{
+			Args:     args,
+			ExitCode: report.Result.ExitCode(),
+			Stderr:   report.Result.FullStderr().Text(),
+		}
+	}
+	return report, nil
+}
+
+// args returns the flags for the options
+func (o RsyncOptions) args() []string {
+	args := []string{outFormat}
+	if o.Archive {
+		args = append(args, "--archive")
+	}
+	if o.Delete {
+		args = append(args, "--delete")
+	}
+	if o.DryRun {
+		args = append(args, "--dry-run")
+	}
+	if o.Compress {
+		args = append(args, "--compress")
+	}
+	for _, pattern := range o.Exclude {
+		args = append(args, "--exclude="+pattern)
+	}
+	return append(args, o.Args...)
+}
+
+// ParseItemized parses a line of rsync's itemized output, as produced
+// with --itemize-changes or an --out-format starting with "%i %n",
+// reporting if the line was one
+func ParseItemized(line string) (Entry, bool) {
+	// an 11 character change summary, a space, then the path
+	if len(line) < 13 || line[11] != ' ' {
+		return Entry{}, false
+	}
+
+	code, path := line[:11], line[12:]
+	if strings.HasPrefix(code, "*deleting") {
+		return Entry{Update: '*', Path: path, Deleted: true}, true
+	}
+
+	if !strings.ContainsRune("<>ch.*", rune(code[0])) || !strings.ContainsRune("fdLDS", rune(code[1])) {
+		return Entry{}, false
+	}
+
+	entry := Entry{
+		Update:     code[0],
+		Type:       code[1],
+		Attributes: strings.TrimRight(code[2:], " "),
+		Path:       path,
+	}
+	if entry.Type == 'L' {
+		if i := strings.Index(path, " -> "); i >= 0 {
+			entry.Path, entry.Target = path[:i], path[i+4:]
+		}
+	}
+	return entry, true
+}
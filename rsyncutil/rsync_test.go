@@ -0,0 +1,107 @@
+package rsyncutil_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/brinick/shell/rsyncutil"
+)
+
+func TestParseItemized(t *testing.T) {
+	tests := []struct {
+		line  string
+		entry rsyncutil.Entry
+		ok    bool
+	}{
+		{">f+++++++++ new.txt", rsyncutil.Entry{Update: '>', Type: 'f', Attributes: "+++++++++", Path: "new.txt"}, true},
+		{">f.st...... dir/changed file", rsyncutil.Entry{Update: '>', Type: 'f', Attributes: ".st......", Path: "dir/changed file"}, true},
+		{"cd+++++++++ dir/", rsyncutil.Entry{Update: 'c', Type: 'd', Attributes: "+++++++++", Path: "dir/"}, true},
+		{"cL+++++++++ link -> target", rsyncutil.Entry{Update: 'c', Type: 'L', Attributes: "+++++++++", Path: "link", Target: "target"}, true},
+		{"*deleting   old.txt", rsyncutil.Entry{Update: '*', Path: "old.txt", Deleted: true}, true},
+		{"sending incremental file list", rsyncutil.Entry{}, false},
+		{"", rsyncutil.Entry{}, false},
+	}
+
+	for _, test := range tests {
+		entry, ok := rsyncutil.ParseItemized(test.line)
+		if ok != test.ok || !reflect.DeepEqual(entry, test.entry) {
+			t.Errorf("%q: expected %+v %v, got %+v %v", test.line, test.entry, test.ok, entry, ok)
+		}
+	}
+
+	if entry, _ := rsyncutil.ParseItemized(">f+++++++++ new.txt"); !entry.New() {
+		t.Error("Expected a new file")
+	}
+	if entry, _ := rsyncutil.ParseItemized(">f.st...... old.txt"); entry.New() {
+		t.Error("Expected an updated file")
+	}
+}
+
+// fakeRsync writes a script standing in for rsync, returning its path
+func fakeRsync(t *testing.T, script string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake rsync is a shell script")
+	}
+
+	dir, err := ioutil.TempDir("", "rsyncutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "rsync")
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRsync(t *testing.T) {
+	path := fakeRsync(t, `echo "$@" > "$(dirname "$0")/args"
+echo "sending incremental file list"
+echo ">f+++++++++ a.txt"
+echo "*deleting   b.txt"`)
+
+	var progress []string
+	report, err := rsyncutil.Rsync("-src/", "dst", rsyncutil.RsyncOptions{
+		Archive:  true,
+		Delete:   true,
+		Exclude:  []string{"*.tmp"},
+		Path:     path,
+		Progress: func(e rsyncutil.Entry) { progress = append(progress, e.Path) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Entries) != 2 || !report.Entries[1].Deleted {
+		t.Errorf("Unexpected entries: %+v", report.Entries)
+	}
+	if !reflect.DeepEqual(progress, []string{"a.txt", "b.txt"}) {
+		t.Errorf("Unexpected progress: %v", progress)
+	}
+
+	args, _ := ioutil.ReadFile(filepath.Join(filepath.Dir(path), "args"))
+	want := "--out-format=%i %n%L --archive --delete --exclude=*.tmp -- -src/ dst\n"
+	if string(args) != want {
+		t.Errorf("Unexpected arguments %q", args)
+	}
+}
+
+func TestRsyncFailure(t *testing.T) {
+	path := fakeRsync(t, `echo "rsync: change_dir failed" >&2; exit 23`)
+
+	report, err := rsyncutil.Rsync("src/", "dst", rsyncutil.RsyncOptions{Path: path})
+	var rerr *rsyncutil.Error
+	if !errors.As(err, &rerr) || rerr.ExitCode != 23 {
+		t.Fatalf("Expected an Error, got %v", err)
+	}
+	if report.Result == nil {
+		t.Error("Expected the Result of the failed command")
+	}
+}
@@ -0,0 +1,27 @@
+package shell
+
+// SandboxPolicy selects the isolation layers applied
+// to a command run with the Sandboxed Option
+type SandboxPolicy struct {
+	// Network runs the command in a fresh network namespace,
+	// with only an unconfigured loopback device
+	Network bool
+
+	// Mount runs the command in a private mount namespace, so that
+	// mounts it makes are not seen by the rest of the system
+	Mount bool
+
+	// PID runs the command in a fresh PID namespace, as its init
+	// process, with /proc remounted to show only its processes.
+	// It implies Mount.
+	PID bool
+
+	// ReadOnly makes every mounted filesystem read-only for the
+	// command. Device files remain writable. It implies Mount.
+	ReadOnly bool
+
+	// Chroot, if set, is the directory made the command's root. It
+	// must contain the shell, as well as mount(8) and awk(1) if PID or
+	// ReadOnly is set.
+	Chroot string
+}
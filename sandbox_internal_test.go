@@ -0,0 +1,91 @@
+package shell
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSandboxedOption(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		result := Run("true", Sandboxed(SandboxPolicy{Network: true}))
+		if !errors.Is(result.Err(), ErrUnsupported) {
+			t.Errorf("Expected ErrUnsupported, got %v", result.Err())
+		}
+		return
+	}
+
+	policy := SandboxPolicy{Network: true, PID: true, ReadOnly: true}
+	result := Run("echo $$; ls /proc | grep -c '^[0-9]'; tail -n +3 /proc/net/dev | wc -l; touch /sandbox-test 2>&1 || echo denied", Sandboxed(policy))
+	if result.IsError() {
+		t.Skipf("Unable to create namespaces: %v", result.Err())
+	}
+
+	lines := result.Stdout().Lines()
+	if len(lines) != 5 {
+		t.Fatalf("Unexpected output: %v %v", lines, result.Stderr().Lines())
+	}
+
+	if lines[0] != "1" {
+		t.Errorf("Expected the command to be the init process, got PID %s", lines[0])
+	}
+	if n, _ := strconv.Atoi(lines[1]); n == 0 || n > 5 {
+		t.Errorf("Expected only the command's own processes, found %s", lines[1])
+	}
+	if strings.TrimSpace(lines[2]) != "1" {
+		t.Errorf("Expected only the loopback device, found %s devices", lines[2])
+	}
+	if lines[4] != "denied" {
+		t.Errorf("Expected the filesystem to be read-only: %v", lines[3:])
+	}
+}
+
+func TestSandboxedMount(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("mount namespaces are only supported on Linux")
+	}
+
+	dir, err := ioutil.TempDir("", "sandbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	result := Run("mount -t tmpfs sandbox "+dir+" && grep -c ' "+dir+" ' /proc/self/mounts", Sandboxed(SandboxPolicy{Mount: true}))
+	if result.IsError() || result.ExitCode() != 0 {
+		t.Skipf("Unable to mount in a new namespace: %v %v", result.Err(), result.Stderr().Lines())
+	}
+	if got := result.Stdout().Text(); got != "1" {
+		t.Errorf("Expected the mount to be made in the sandbox, got %q", got)
+	}
+
+	mounts, err := ioutil.ReadFile("/proc/self/mounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(mounts), " "+dir+" ") {
+		t.Errorf("Expected the sandbox's mount not to propagate to the host")
+	}
+}
+
+func TestSandboxOption(t *testing.T) {
+	if runtime.GOOS != "darwin" {
+		result := Run("true", Sandbox("(version 1)(allow default)"))
+		if !errors.Is(result.Err(), ErrUnsupported) {
+			t.Errorf("Expected ErrUnsupported, got %v", result.Err())
+		}
+		return
+	}
+
+	result := Run("echo sandboxed", Sandbox("(version 1)(allow default)"))
+	if result.IsError() {
+		t.Skipf("Unable to run sandbox-exec: %v", result.Err())
+	}
+	if got := result.Stdout().Text(); got != "sandboxed" {
+		t.Errorf("Expected the command to run in the sandbox, got %q", got)
+	}
+}
@@ -0,0 +1,77 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// shell snippets run by the launcher inside the new namespaces,
+// before it executes the command
+const (
+	// stop mounts propagating back to the rest of the system
+	sandboxPrivate   = `mount --make-rprivate / || exit 125; `
+	sandboxMountProc = `mount -t proc proc /proc || exit 125; `
+
+	// remount each filesystem read-only, keeping the flags that may be
+	// locked in a user namespace, and failing if any cannot be
+	sandboxReadOnly = `awk '{print $2, $4}' /proc/self/mounts | while read -r m o; do ` +
+		`m=$(printf '%b' "$m"); f=remount,bind,ro; ` +
+		`for x in nosuid nodev noexec noatime nodiratime relatime strictatime; do ` +
+		`case ",$o," in *",$x,"*) f=$f,$x;; esac; done; ` +
+		`mount -o "$f" "$m" || exit 125; done || exit 125; `
+)
+
+// Sandboxed is an Option to run the command isolated from the rest
+// of the system, using Linux namespaces, as set out by the policy.
+// When not running as root, a user namespace is created as well so
+// that the other namespaces can be set up unprivileged.
+func Sandboxed(policy SandboxPolicy) Option {
+	return func(s *command) {
+		var flags uintptr
+		if policy.Network {
+			flags |= syscall.CLONE_NEWNET
+		}
+		if policy.Mount || policy.PID || policy.ReadOnly {
+			flags |= syscall.CLONE_NEWNS
+		}
+		if policy.PID {
+			flags |= syscall.CLONE_NEWPID
+		}
+
+		s.beforeExec = append(s.beforeExec, func(c *exec.Cmd) {
+			if c.SysProcAttr == nil {
+				c.SysProcAttr = &syscall.SysProcAttr{}
+			}
+
+			attr := c.SysProcAttr
+			attr.Cloneflags |= flags
+			if policy.Chroot != "" {
+				attr.Chroot = policy.Chroot
+			}
+
+			if flags != 0 && os.Geteuid() != 0 {
+				attr.Cloneflags |= syscall.CLONE_NEWUSER
+				attr.UidMappings = []syscall.SysProcIDMap{
+					{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1},
+				}
+				attr.GidMappings = []syscall.SysProcIDMap{
+					{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1},
+				}
+			}
+		})
+
+		// mounts can only be adjusted from within the namespaces,
+		// so a launcher does so before executing the command
+		setup := sandboxPrivate
+		if policy.PID {
+			setup += sandboxMountProc
+		}
+		if policy.ReadOnly {
+			setup += sandboxReadOnly
+		}
+		if flags&syscall.CLONE_NEWNS != 0 {
+			s.prefix = append(s.prefix, "/bin/sh", "-c", setup+`exec "$@"`, "sandbox")
+		}
+	}
+}
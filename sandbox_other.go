@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package shell
+
+import "fmt"
+
+// Sandboxed is an Option to run the command isolated from the rest
+// of the system as set out by the policy. It is only supported on Linux.
+func Sandboxed(policy SandboxPolicy) Option {
+	return func(s *command) {
+		s.err = fmt.Errorf("Sandboxed: %w", ErrUnsupported)
+	}
+}
@@ -0,0 +1,10 @@
+package shell
+
+// Sandbox is an Option to confine the command with sandbox-exec(1)
+// using the given profile, which is the SBPL text itself rather than
+// the path to a profile file.
+func Sandbox(profile string) Option {
+	return func(s *command) {
+		s.prefix = append(s.prefix, "/usr/bin/sandbox-exec", "-p", profile)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !darwin
+// +build !darwin
+
+package shell
+
+import "fmt"
+
+// Sandbox is an Option to confine the command with sandbox-exec(1)
+// using the given profile. It is only supported on macOS; elsewhere
+// the command fails with an error wrapping ErrUnsupported.
+func Sandbox(profile string) Option {
+	return func(s *command) {
+		s.err = fmt.Errorf("Sandbox: %w", ErrUnsupported)
+	}
+}
@@ -0,0 +1,85 @@
+package shell
+
+// sequenceMode decides whether a Sequence moves on to its next command
+type sequenceMode int
+
+const (
+	sequenceThen sequenceMode = iota // always
+	sequenceAnd                      // after a success
+	sequenceOr                       // after a failure
+)
+
+// Sequence is a list of commands run one after the other
+type Sequence struct {
+	commands []string
+	mode     sequenceMode
+}
+
+// And creates the Sequence running each command only if all those
+// before it succeeded, as with "a && b && c" in the shell
+func And(commands ...string) *Sequence {
+	return &Sequence{commands: commands, mode: sequenceAnd}
+}
+
+// Or creates the Sequence running each command only if all those
+// before it failed, as with "a || b || c" in the shell
+func Or(commands ...string) *Sequence {
+	return &Sequence{commands: commands, mode: sequenceOr}
+}
+
+// Then creates the Sequence running every command regardless
+// of the outcome of the others, as with "a; b; c" in the shell
+func Then(commands ...string) *Sequence {
+	return &Sequence{commands: commands, mode: sequenceThen}
+}
+
+// Run executes the commands, each configured with the Options,
+// and returns once the last of them to run is done. A command
+// succeeds if it exits with code 0 and without error.
+func (s *Sequence) Run(options ...Option) *SequenceResult {
+	res := &SequenceResult{total: len(s.commands)}
+	for _, command := range s.commands {
+		step := Run(command, options...)
+		<-step.Ready()
+		res.Steps = append(res.Steps, step)
+
+		ok := step.ExitCode() == 0 && !step.IsError()
+		if (s.mode == sequenceAnd && !ok) || (s.mode == sequenceOr && ok) {
+			break
+		}
+	}
+	return res
+}
+
+// ------------------------------------------------------------------
+
+// SequenceResult holds the Results of the commands of a
+// Sequence that were run, in order
+type SequenceResult struct {
+	Steps []*Result
+	total int
+}
+
+// Skipped returns the number of commands that were not run
+func (r *SequenceResult) Skipped() int {
+	return r.total - len(r.Steps)
+}
+
+// ExitCode returns the exit code of the last command run,
+// or 0 if there were no commands
+func (r *SequenceResult) ExitCode() int {
+	if len(r.Steps) == 0 {
+		return 0
+	}
+	return r.Steps[len(r.Steps)-1].ExitCode()
+}
+
+// Err returns the first error from running any of the commands
+func (r *SequenceResult) Err() error {
+	for _, step := range r.Steps {
+		if err := step.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
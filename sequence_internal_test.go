@@ -0,0 +1,42 @@
+package shell
+
+import "testing"
+
+func TestSequences(t *testing.T) {
+	tests := []struct {
+		name     string
+		seq      *Sequence
+		ran      int
+		exitCode int
+	}{
+		{"And all succeed", And("true", "true", "exit 0"), 3, 0},
+		{"And stops at failure", And("true", "exit 3", "true"), 2, 3},
+		{"Or stops at success", Or("exit 1", "true", "exit 2"), 2, 0},
+		{"Or all fail", Or("exit 1", "exit 2"), 2, 2},
+		{"Then runs all", Then("exit 1", "exit 2", "exit 5"), 3, 5},
+		{"empty", And(), 0, 0},
+	}
+
+	for _, test := range tests {
+		res := test.seq.Run()
+		if len(res.Steps) != test.ran {
+			t.Errorf("%s: expected %d commands to run, got %d", test.name, test.ran, len(res.Steps))
+		}
+		if res.Skipped() != len(test.seq.commands)-test.ran {
+			t.Errorf("%s: unexpected number skipped: %d", test.name, res.Skipped())
+		}
+		if res.ExitCode() != test.exitCode {
+			t.Errorf("%s: expected exit code %d, got %d", test.name, test.exitCode, res.ExitCode())
+		}
+	}
+}
+
+func TestSequenceOutput(t *testing.T) {
+	res := And("echo one", "echo two").Run(Tag("seq"))
+	if err := res.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Steps[1].Stdout().Text(); got != "two" {
+		t.Errorf("Expected each step to keep its own output, got %q", got)
+	}
+}
@@ -0,0 +1,285 @@
+package shell
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-cmd/cmd"
+)
+
+// ErrSessionClosed is returned by commands run in
+// a Session whose shell is no longer running
+var ErrSessionClosed = errors.New("session closed")
+
+// Session is a long-lived bash process running commands one after
+// the other, so that state such as the working directory, exported
+// variables and shell variables carries over from one to the next.
+// It is not supported on Windows.
+type Session struct {
+	shell  *Result
+	marker string
+
+	// held for the duration of each command
+	running sync.Mutex
+
+	// the command whose output is being received
+	mu      sync.Mutex
+	current *sessionProcess
+}
+
+// NewSession starts the shell of a new Session. The Options apply
+// to the shell itself, e.g. Env to set its initial environment.
+func NewSession(options ...Option) (*Session, error) {
+	if runtime.GOOS == "windows" {
+		return nil, fmt.Errorf("NewSession: %w", ErrUnsupported)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	s := &Session{marker: fmt.Sprintf("__shell_session_%x__", id)}
+	options = append(options, Interactive(), Bkgd(), OnStdoutLine(s.stdoutLine), OnStderrLine(s.stderrLine))
+	s.shell = RunArgs(shellExe, []string{"-s"}, options...)
+	if s.shell.IsReady() {
+		if err := s.shell.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrSessionClosed
+	}
+	return s, nil
+}
+
+// Run executes the command in the session's shell, once any
+// commands already running in it are done. The command reads no
+// stdin. Options applying to the process itself, such as Env or
+// NoNetwork, are ignored; the command can change the session's
+// environment itself. Timeout and Cancel stop the processes the
+// command started, on Linux, and the session carries on.
+func (s *Session) Run(command string, options ...Option) *Result {
+	options = append(options, WithBackend(s.backend))
+	return Run(command, options...)
+}
+
+// Close ends the session, once any running commands are done,
+// and returns an error if its shell did not exit cleanly
+func (s *Session) Close() error {
+	s.running.Lock()
+	defer s.running.Unlock()
+
+	s.shell.Stdin().Close()
+	<-s.shell.Ready()
+	if err := s.shell.Err(); err != nil {
+		return err
+	}
+	if code := s.shell.ExitCode(); code != 0 {
+		return fmt.Errorf("session shell exited with code %d", code)
+	}
+	return nil
+}
+
+// backend creates the Process running the command in the session
+func (s *Session) backend(name string, args []string) Process {
+	// Run hands over the command as the argument of -c
+	command := args[len(args)-1]
+	return &sessionProcess{
+		session: s,
+		command: command,
+		done:    make(chan struct{}),
+		status: cmd.Status{
+			Cmd:  command,
+			Exit: -1,
+		},
+	}
+}
+
+// script returns the text sent to the shell to run the command,
+// followed by the markers signalling that it is done
+func (s *Session) script(command string) string {
+	quoted := "'" + strings.Replace(command, "'", `'\''`, -1) + "'"
+	return fmt.Sprintf("{ eval %s\n} </dev/null; printf '%%s %%d\\n' %s \"$?\"; printf '%%s\\n' %s >&2\n",
+		quoted, s.marker, s.marker)
+}
+
+func (s *Session) stdoutLine(line string) {
+	s.mu.Lock()
+	p := s.current
+	s.mu.Unlock()
+	if p == nil {
+		return
+	}
+
+	// the marker follows any final output without a newline
+	if i := strings.LastIndex(line, s.marker+" "); i >= 0 {
+		code, err := strconv.Atoi(line[i+len(s.marker)+1:])
+		if err == nil {
+			p.finish(line[:i], &p.status.Stdout, code)
+			return
+		}
+	}
+	p.emit(&p.status.Stdout, line)
+}
+
+func (s *Session) stderrLine(line string) {
+	s.mu.Lock()
+	p := s.current
+	s.mu.Unlock()
+	if p == nil {
+		return
+	}
+
+	if strings.HasSuffix(line, s.marker) {
+		p.finish(strings.TrimSuffix(line, s.marker), &p.status.Stderr, -1)
+		return
+	}
+	p.emit(&p.status.Stderr, line)
+}
+
+// ------------------------------------------------------------------
+
+// sessionProcess is the Process of a command run in a Session
+type sessionProcess struct {
+	session *Session
+	command string
+
+	mu         sync.Mutex
+	status     cmd.Status
+	statusChan chan cmd.Status
+	started    time.Time
+	markers    int // of stdout and stderr, received so far
+	complete   chan struct{}
+	stopped    bool
+	done       chan struct{}
+}
+
+func (p *sessionProcess) Start() <-chan cmd.Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.statusChan == nil {
+		p.statusChan = make(chan cmd.Status, 1)
+		p.complete = make(chan struct{})
+		go p.run()
+	}
+	return p.statusChan
+}
+
+// Stop terminates the processes started by the command
+func (p *sessionProcess) Stop() error {
+	p.mu.Lock()
+	if p.statusChan == nil {
+		p.mu.Unlock()
+		return cmd.ErrNotStarted
+	}
+	p.stopped = true
+	p.mu.Unlock()
+
+	// the shell's PID is only known once it has been launched
+	return stopDescendants(p.session.shell.PID())
+}
+
+func (p *sessionProcess) Status() cmd.Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	st := p.status
+	st.Stdout = append([]string{}, p.status.Stdout...)
+	st.Stderr = append([]string{}, p.status.Stderr...)
+	if !p.started.IsZero() && st.StopTs == 0 {
+		st.Runtime = time.Since(p.started).Seconds()
+	}
+	return st
+}
+
+func (p *sessionProcess) Done() <-chan struct{} {
+	return p.done
+}
+
+// run waits for its turn in the session, then sends the
+// command to the shell and waits for it to be done
+func (p *sessionProcess) run() {
+	s := p.session
+	s.running.Lock()
+
+	p.mu.Lock()
+	p.started = time.Now()
+	p.status.StartTs = p.started.UnixNano()
+	p.status.PID = s.shell.PID()
+	stopped := p.stopped
+	p.mu.Unlock()
+
+	var err error
+	if !stopped {
+		s.mu.Lock()
+		s.current = p
+		s.mu.Unlock()
+
+		if s.shell.IsReady() {
+			err = ErrSessionClosed
+		} else if _, werr := s.shell.Stdin().Write([]byte(s.script(p.command))); werr != nil {
+			err = fmt.Errorf("%w: %v", ErrSessionClosed, werr)
+		} else {
+			select {
+			case <-p.complete:
+			case <-s.shell.Ready():
+				err = ErrSessionClosed
+			}
+		}
+
+		s.mu.Lock()
+		s.current = nil
+		s.mu.Unlock()
+	}
+	s.running.Unlock()
+
+	now := time.Now()
+	p.mu.Lock()
+	switch {
+	case err != nil:
+		p.status.Error = err
+	case p.stopped && p.status.Exit != 0:
+		p.status.Error = errors.New("signal: terminated")
+	default:
+		p.status.Complete = true
+	}
+	p.status.StopTs = now.UnixNano()
+	p.status.Runtime = now.Sub(p.started).Seconds()
+	p.mu.Unlock()
+
+	p.statusChan <- p.Status()
+	close(p.done)
+}
+
+// emit adds a line of output
+func (p *sessionProcess) emit(out *[]string, line string) {
+	p.mu.Lock()
+	*out = append(*out, line)
+	p.mu.Unlock()
+}
+
+// finish records the end of one of the output streams, preceded by
+// partial, any output without a trailing newline. The stdout marker
+// carries the exit code, which is -1 for the stderr marker.
+func (p *sessionProcess) finish(partial string, out *[]string, code int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if partial != "" {
+		*out = append(*out, partial)
+	}
+	if code >= 0 {
+		p.status.Exit = code
+	}
+
+	p.markers++
+	if p.markers == 2 {
+		close(p.complete)
+	}
+}
@@ -0,0 +1,73 @@
+package shell
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSession(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		if _, err := NewSession(); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Expected ErrUnsupported, got %v", err)
+		}
+		return
+	}
+
+	s, err := NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Run("cd /tmp")
+	s.Run("export GREETING=hello; count=41")
+	res := s.Run("pwd; echo $GREETING $((count+1)); echo warning >&2; printf 'partial'")
+	if got := res.Stdout().Lines(); len(got) != 3 || got[0] != "/tmp" || got[1] != "hello 42" || got[2] != "partial" {
+		t.Errorf("Expected state to carry over between commands, got %q", got)
+	}
+	if got := res.Stderr().Text(); got != "warning" {
+		t.Errorf("Unexpected stderr: %q", got)
+	}
+
+	if res := s.Run("(exit 3)"); res.ExitCode() != 3 || res.IsError() {
+		t.Errorf("Expected exit code 3, got %d: %v", res.ExitCode(), res.Err())
+	}
+
+	if res := s.Run("echo 'quoted $HOME'; if then"); res.ExitCode() == 0 {
+		t.Errorf("Expected a syntax error to fail the command: %v", res.Stdout().Lines())
+	}
+
+	if err := s.Close(); err != nil {
+		t.Errorf("Unexpected error closing the session: %v", err)
+	}
+	if res := s.Run("true"); !errors.Is(res.Err(), ErrSessionClosed) {
+		t.Errorf("Expected ErrSessionClosed, got %v", res.Err())
+	}
+}
+
+func TestSessionTimeout(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("stopping session commands relies on /proc")
+	}
+
+	s, err := NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	start := time.Now()
+	res := s.Run("sleep 5", Timeout(100*time.Millisecond))
+	<-res.Ready()
+	if !res.TimedOut() {
+		t.Error("Expected the command to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the command to be stopped, took %v", elapsed)
+	}
+
+	if got := s.Run("echo alive").Stdout().Text(); got != "alive" {
+		t.Errorf("Expected the session to survive, got %q", got)
+	}
+}
@@ -2,9 +2,17 @@ package shell
 
 import (
 	"context"
+	"crypto"
+	"errors"
 	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
 	"os"
+	"os/exec"
+	"runtime/pprof"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-cmd/cmd"
@@ -12,13 +20,84 @@ import (
 
 // ------------------------------------------------------------------
 
+// ErrUnsupported is returned, wrapped, by commands configured
+// with an Option that is not available on the current platform
+var ErrUnsupported = errors.New("not supported on this platform")
+
+// The reasons a command was stopped, as reported by Result.Err
+// wrapped in a TerminationError
+var (
+	ErrTimeout  = errors.New("timed out")
+	ErrCanceled = errors.New("canceled")
+	ErrCrashed  = errors.New("crashed")
+)
+
+// TerminationError is returned by Result.Err when the command was
+// stopped before it ended by itself. It matches its Reason with
+// errors.Is, and unwraps to its cause.
+type TerminationError struct {
+	Reason error // ErrTimeout, ErrCanceled or ErrCrashed
+	Err    error // the cause, e.g. the signal stopping the process, or nil
+}
+
+func (e *TerminationError) Error() string {
+	if e.Err == nil {
+		return e.Reason.Error()
+	}
+	return e.Reason.Error() + ": " + e.Err.Error()
+}
+
+// Is reports if target is the reason the command was stopped
+func (e *TerminationError) Is(target error) bool {
+	return target == e.Reason
+}
+
+// Unwrap returns the cause
+func (e *TerminationError) Unwrap() error {
+	return e.Err
+}
+
+// ------------------------------------------------------------------
+
 // Run executes the command and returns a Result object.
 // The command can be configured via one or more Option functions.
+// Commands are run by bash, or by cmd.exe on Windows.
 func Run(command string, options ...Option) *Result {
-	exe := "/bin/bash"
-	args := append([]string{"-c"}, fmt.Sprintf("%s", command))
+	shellcmd := newShellCommand(command, options...)
+	shellcmd.Result.command = command
+	shellcmd.run()
+	return shellcmd.Result
+}
 
+// RunArgs executes the program directly with the given arguments,
+// without going through a shell. Nothing in args is interpreted,
+// so it is safe to pass values that come from user input.
+func RunArgs(exe string, args []string, options ...Option) *Result {
 	shellcmd := newCommand(exe, args, options...)
+	shellcmd.Result.command = strings.Join(append([]string{exe}, args...), " ")
+	shellcmd.run()
+	return shellcmd.Result
+}
+
+// RunStdinScript executes the interpreter, feeding it the script on
+// its stdin. The interpreter is itself run by the shell, so may include
+// arguments, e.g. "python3 -". This avoids both writing the script to a
+// temporary file and the quoting needed to embed it in a command string.
+func RunStdinScript(interpreter string, script string, options ...Option) *Result {
+	options = append(options, StdinString(script))
+	shellcmd := newShellCommand(interpreter, options...)
+	shellcmd.Result.command = interpreter
+	shellcmd.run()
+	return shellcmd.Result
+}
+
+// RunWithArgs executes the command, passing args to it as the
+// positional parameters $1, $2, ... rather than as part of the
+// script text, so that they are never interpreted by the shell.
+// It is not supported on Windows.
+func RunWithArgs(command string, args []string, options ...Option) *Result {
+	shellcmd := newShellCommandWithArgs(command, args, options...)
+	shellcmd.Result.command = command
 	shellcmd.run()
 	return shellcmd.Result
 }
@@ -27,6 +106,9 @@ func Run(command string, options ...Option) *Result {
 
 // Result is the wrapper
 type Result struct {
+	command string
+	tags    []string
+
 	// Something panicked - process died
 	crashed     bool
 	crashReason string
@@ -40,9 +122,46 @@ type Result struct {
 	current func() cmd.Status
 	final   *cmd.Status
 	done    func() <-chan struct{}
+	stop    func()
 
 	nStdout int
 	nStderr int
+
+	// running digest of stdout, if requested
+	digest hash.Hash
+
+	// output captured by the package rather than by go-cmd
+	stdoutBuf lineBuffer
+	stderrBuf lineBuffer
+
+	// both streams interleaved, if requested
+	transcript *transcript
+
+	// channels delivering output as it arrives, if requested
+	streams *streams
+
+	// writable end of the command's stdin, if requested
+	stdin io.WriteCloser
+
+	// resources limited by the Rlimit Option
+	rlimits []int
+
+	// soft CPU time limit set with the Rlimit Option, and the process
+	// whose CPU time is checked against it once it is done
+	cpuLimit time.Duration
+	process  *exec.Cmd
+
+	// usage measured by the cgroup, if requested
+	cgroupUsage *CgroupUsage
+
+	// environment the command was given, nil if inherited
+	env []string
+
+	// identifier stamped into the environment, if requested
+	runID string
+
+	// whether a non-zero exit code is reported by Err
+	failOnNonZero bool
 }
 
 // IsReady returns a bool indicating if the command
@@ -65,6 +184,34 @@ func (r *Result) Ready() <-chan struct{} {
 	return r.done()
 }
 
+// Wait blocks until the command is done, as it may not be if it was
+// run with Bkgd, and returns the Result with the error, if any, from
+// running the command
+func (r *Result) Wait() (*Result, error) {
+	<-r.done()
+	return r, r.Err()
+}
+
+// Kill stops the command, as if it were canceled, honouring any
+// KillGrace period, and returns once it is done. It is meant for
+// commands run with Bkgd, others being done by the time their Result
+// is returned.
+func (r *Result) Kill() {
+	r.stop()
+	<-r.done()
+}
+
+// WaitContext is like Wait, but gives up once ctx is done, returning
+// the context's error. The command is left running.
+func (r *Result) WaitContext(ctx context.Context) (*Result, error) {
+	select {
+	case <-r.done():
+		return r, r.Err()
+	case <-ctx.Done():
+		return r, ctx.Err()
+	}
+}
+
 func (r *Result) status() *cmd.Status {
 	if r.final != nil {
 		return r.final
@@ -92,29 +239,91 @@ func (r *Result) Duration() float64 {
 // Stdout returns an Output object wrapping the latest lines
 // from the stdout stream
 func (r *Result) Stdout() *Output {
-	lines := r.status().Stdout[r.nStdout:]
-	r.nStdout += len(lines)
-	return &Output{lines}
+	lines, dropped := window(r.stdoutBuf, r.stdoutLines)
+	return &Output{unread(lines, dropped, &r.nStdout)}
 }
 
 // Stderr returns an Output object wrapping the latest lines
 // from the stderr stream
 func (r *Result) Stderr() *Output {
-	lines := r.status().Stderr[r.nStderr:]
-	r.nStderr += len(lines)
-	return &Output{lines}
+	lines, dropped := window(r.stderrBuf, r.stderrLines)
+	return &Output{unread(lines, dropped, &r.nStderr)}
+}
+
+// FullStdout returns an Output object wrapping all lines written to
+// stdout so far, regardless of those already returned by Stdout
+func (r *Result) FullStdout() *Output {
+	return &Output{r.stdoutLines()}
+}
+
+// FullStderr returns an Output object wrapping all lines written to
+// stderr so far, regardless of those already returned by Stderr
+func (r *Result) FullStderr() *Output {
+	return &Output{r.stderrLines()}
+}
+
+// StdoutBytes returns everything written to stdout so far, byte for
+// byte, or nil if the output was not kept raw with RawOutput or PTY
+func (r *Result) StdoutBytes() []byte {
+	if b, ok := r.stdoutBuf.(*memoryBuffer); ok {
+		return b.Bytes()
+	}
+	return nil
+}
+
+// StderrBytes returns everything written to stderr so far, byte for
+// byte, or nil if the output was not kept raw with RawOutput
+func (r *Result) StderrBytes() []byte {
+	if b, ok := r.stderrBuf.(*memoryBuffer); ok {
+		return b.Bytes()
+	}
+	return nil
+}
+
+func (r *Result) stdoutLines() []string {
+	if r.stdoutBuf != nil {
+		return r.stdoutBuf.Lines()
+	}
+	return r.status().Stdout
+}
+
+func (r *Result) stderrLines() []string {
+	if r.stderrBuf != nil {
+		return r.stderrBuf.Lines()
+	}
+	return r.status().Stderr
 }
 
 // IsError indicates if any error occured in preparing or executing
 // the shell command. This will return false if the command ran ok,
-// but just had a non-zero exit code.
+// but just had a non-zero exit code, unless FailOnNonZero was given.
 func (r *Result) IsError() bool {
 	return r.Err() != nil
 }
 
-// Err returns an eventual error from running the command
+// Err returns an eventual error from running the command. If the
+// command crashed, timed out or was canceled, it is a TerminationError.
 func (r *Result) Err() error {
-	return r.status().Error
+	err := r.status().Error
+
+	var reason error
+	switch {
+	case r.crashed:
+		reason = ErrCrashed
+		if err == nil {
+			err = errors.New(r.crashReason)
+		}
+	case r.timedOut:
+		reason = ErrTimeout
+	case r.canceled:
+		reason = ErrCanceled
+	default:
+		if err == nil && r.failOnNonZero {
+			err = r.exitError()
+		}
+		return err
+	}
+	return &TerminationError{Reason: reason, Err: err}
 }
 
 // Crashed indicates if the command crashed
@@ -138,6 +347,33 @@ func (r *Result) TimedOut() bool {
 	return r.timedOut
 }
 
+// Command returns the command that was run
+func (r *Result) Command() string {
+	return r.command
+}
+
+// Tags returns the tags attached to the command with the Tag Option
+func (r *Result) Tags() []string {
+	return r.tags
+}
+
+// OutputDigest returns the digest of everything the command wrote
+// to stdout, as computed by the HashOutput Option. It returns nil if
+// no digest was requested, or the command is not yet done.
+func (r *Result) OutputDigest() []byte {
+	if r.digest == nil || !r.IsReady() {
+		return nil
+	}
+	return r.digest.Sum(nil)
+}
+
+// Stdin returns a writer connected to the standard input of a
+// command run with the Interactive Option, or nil otherwise.
+// Closing it signals end of input to the command.
+func (r *Result) Stdin() io.WriteCloser {
+	return r.stdin
+}
+
 // ------------------------------------------------------------------
 
 // Output is a structure to wrap the shell command output stream
@@ -164,15 +400,87 @@ func (o *Output) Text() string {
 
 // command represents a given shell command
 type command struct {
-	c      *cmd.Cmd // the wrapped command object
-	Result *Result  // the result object
+	c      Process // the wrapped command object
+	Result *Result // the result object
+
+	exe  string
+	args []string
 
 	// options
+	prefix  []string // launcher (and its args) to run the executable through
 	env     []string
 	ctx     context.Context
 	stop    <-chan struct{}
 	bkgd    bool
 	timeout time.Duration // 0 = no timeout
+	grace   time.Duration // 0 = never escalate to SIGKILL
+	group   bool          // kill the whole process tree when stopping
+	clock   Clock
+	secrets []secret
+	backend Backend
+	user    *credential // nil = run as the current user
+	logger  Logger      // nil = no logging
+	tracer  Tracer      // nil = no tracing
+	span    Span        // the command's span, when traced
+
+	// functions to customise the underlying exec.Cmd before launch
+	beforeExec []func(*exec.Cmd)
+
+	// source of the command's stdin, if any
+	stdin io.Reader
+
+	// attaches the command to its pseudo-terminal, if any,
+	// once all other customisations have been made
+	terminal func(*exec.Cmd)
+
+	// decoding of the output to UTF-8 before anything else sees it, if any
+	encoding *encoding
+
+	// functions sending output straight to a file, bypassing the
+	// package, once all other customisations have been made
+	redirect []func(*exec.Cmd)
+
+	// functions run in their own goroutine while the command runs
+	monitors []func()
+
+	// CI grouping of the output, if requested
+	ci *ciDecorator
+
+	// callbacks for each line of output
+	onStdout []func(string)
+	onStderr []func(string)
+
+	// callbacks for the command's lifecycle events
+	onStart []func(int)
+	onExit  []func(*Result)
+	onKill  []func(string)
+
+	// closed once the launch has been reported, if it is to be
+	launched chan struct{}
+
+	// additional destinations for the output streams
+	stdout []io.Writer
+	stderr []io.Writer
+
+	// an Option that could not be honoured
+	err error
+
+	// functions to call just before launch
+	setups []func() error
+
+	// functions to call once the command is done
+	cleanups []func()
+	done     chan struct{}
+
+	// closed by Result.Kill
+	killed   chan struct{}
+	killOnce sync.Once
+}
+
+// secret is a value handed to the command via a private file
+type secret struct {
+	key   string
+	value string
 }
 
 // ------------------------------------------------------------------
@@ -181,25 +489,162 @@ type command struct {
 func newCommand(executable string, args []string, options ...Option) *command {
 	s := &command{
 		ctx:    context.TODO(),
-		c:      cmd.NewCmd(executable, args...),
+		clock:  realClock{},
+		exe:    executable,
+		args:   args,
 		Result: &Result{},
+		done:   make(chan struct{}),
+		killed: make(chan struct{}),
 	}
 
 	for _, option := range options {
 		option(s)
 	}
 
-	s.c.Env = s.env
-	s.Result.done = s.c.Done
-	s.Result.current = s.c.Status
-
+	s.Result.done = func() <-chan struct{} { return s.done }
+	s.Result.stop = func() { s.killOnce.Do(func() { close(s.killed) }) }
 	return s
 }
 
 // ------------------------------------------------------------------
 
-// run will launch the given shell command, returning once the command is done
+// newProcess creates the process that will execute the command
+func (sc *command) newProcess() Process {
+	sc.Result.env = sc.env
+	name, args := sc.exe, sc.args
+	if len(sc.prefix) > 0 {
+		name = sc.prefix[0]
+		args = append([]string{}, sc.prefix[1:]...)
+		args = append(append(args, sc.exe), sc.args...)
+	}
+
+	// decoded output is captured by the package, as the end of it
+	// is only flushed once go-cmd has stopped accepting output
+	if sc.encoding != nil {
+		if sc.Result.stdoutBuf == nil {
+			sc.Result.stdoutBuf = newMemoryBuffer()
+			sc.Result.stderrBuf = newMemoryBuffer()
+		}
+		sc.cleanups = append(sc.cleanups, sc.encoding.flush)
+	}
+
+	// output captured by the package itself replaces go-cmd's buffers,
+	// and discarded output need not be written anywhere
+	buffered := true
+	if _, discard := sc.Result.stdoutBuf.(discardBuffer); discard {
+		buffered = false
+	} else if sc.Result.stdoutBuf != nil {
+		buffered = false
+		sc.stdout = append(sc.stdout, sc.Result.stdoutBuf)
+		sc.stderr = append(sc.stderr, sc.Result.stderrBuf)
+		sc.cleanups = append(sc.cleanups, func() {
+			sc.Result.stdoutBuf.Close()
+			sc.Result.stderrBuf.Close()
+		})
+	}
+
+	if t := sc.Result.transcript; t != nil {
+		t.clock = sc.clock
+		sc.addLineWriters(t.writer(Stdout), t.writer(Stderr))
+	}
+
+	if sc.Result.streams != nil {
+		sc.addStreams()
+	}
+
+	if len(sc.onStdout) > 0 || len(sc.onStderr) > 0 {
+		sc.addLineCallbacks()
+	}
+
+	if sc.ci != nil {
+		sc.ci.open(sc.Result.command)
+		sc.addLineWriters(sc.ci.writer(), sc.ci.writer())
+		sc.cleanups = append(sc.cleanups, func() {
+			sc.ci.close(sc.Result.command, sc.Result)
+		})
+	}
+
+	if sc.backend != nil {
+		return sc.backendProcess(name, args)
+	}
+
+	beforeExec := sc.beforeExec
+	if sc.stdin != nil {
+		beforeExec = append(beforeExec, func(c *exec.Cmd) {
+			c.Stdin = sc.stdin
+		})
+	}
+
+	if len(sc.stdout) > 0 || len(sc.stderr) > 0 {
+		beforeExec = append(beforeExec, func(c *exec.Cmd) {
+			c.Stdout = multiWriter(c.Stdout, sc.stdout...)
+			c.Stderr = multiWriter(c.Stderr, sc.stderr...)
+		})
+	}
+
+	if e := sc.encoding; e != nil {
+		beforeExec = append(beforeExec, func(c *exec.Cmd) {
+			c.Stdout = e.wrap(c.Stdout)
+			c.Stderr = e.wrap(c.Stderr)
+		})
+	}
+
+	beforeExec = append(beforeExec, sc.redirect...)
+	if sc.terminal != nil {
+		beforeExec = append(beforeExec, sc.terminal)
+	}
+
+	c := cmd.NewCmdOptions(
+		cmd.Options{
+			Buffered:   buffered,
+			BeforeExec: beforeExec,
+		},
+		name,
+		args...,
+	)
+	c.Env = sc.env
+	return c
+}
+
+// ------------------------------------------------------------------
+
+// addLineWriters adds writers to the output streams
+// that are closed once the command is done
+func (sc *command) addLineWriters(stdout, stderr *lineWriter) {
+	sc.stdout = append(sc.stdout, stdout)
+	sc.stderr = append(sc.stderr, stderr)
+	sc.cleanups = append(sc.cleanups, func() {
+		stdout.Close()
+		stderr.Close()
+	})
+}
+
+// ------------------------------------------------------------------
+
+// run will launch the given shell command, returning once the command is done.
+// Goroutines servicing the command carry pprof labels identifying it.
 func (sc *command) run() {
+	sc.traceStart()
+	pprof.Do(sc.ctx, sc.profileLabels(), func(context.Context) {
+		sc.launch()
+	})
+}
+
+// profileLabels returns the pprof labels attached to the command's goroutines
+func (sc *command) profileLabels() pprof.LabelSet {
+	name := sc.exe
+	if fields := strings.Fields(sc.Result.command); len(fields) > 0 {
+		name = fields[0]
+	}
+
+	if len(sc.Result.tags) == 0 {
+		return pprof.Labels("shell.command", name)
+	}
+	return pprof.Labels("shell.command", name, "shell.tag", strings.Join(sc.Result.tags, ","))
+}
+
+// launch starts the command, and waits for it unless it is to run in the background
+func (sc *command) launch() {
 	defer func() {
 		if r := recover(); r != nil {
 			sc.Result.crashed = true
@@ -207,48 +652,185 @@ func (sc *command) run() {
 		}
 	}()
 
-	var (
-		timeoutAdded = make(chan struct{})
-		statusChan   <-chan cmd.Status
-	)
+	if err := sc.prepare(); err != nil {
+		sc.fail(err)
+		return
+	}
+
+	sc.c = sc.newProcess()
+	sc.Result.current = sc.c.Status
+
+	var expired <-chan time.Time
+	if sc.timeout > 0 {
+		expired = sc.clock.After(sc.timeout)
+	}
 
+	statusChan := sc.c.Start()
+	trackStart(sc.Result)
+	if len(sc.onStart) > 0 || sc.logger != nil {
+		sc.launched = make(chan struct{})
+		go sc.started()
+	}
 	go func() {
-		if sc.timeout > 0 {
-			// augment the context
-			var cancelTimeout context.CancelFunc
-			sc.ctx, cancelTimeout = context.WithTimeout(sc.ctx, sc.timeout)
-			defer cancelTimeout()
+		<-sc.c.Done()
+		if sc.launched != nil {
+			<-sc.launched
 		}
-		close(timeoutAdded)
-
-		// exit only when the command is done, so as not to
-		// prematurely invoke the deferred timeout cancel
-		<-sc.Result.Ready()
+		final := sc.c.Status()
+		sc.Result.final = &final
+		sc.cleanup()
+		trackDone(sc.Result)
+		sc.logDone()
+		sc.traceDone()
+		close(sc.done)
+		sc.exited()
 	}()
 
-	// block until the other routine has augmented
-	// any timeout to the shell command instance
-	<-timeoutAdded
+	for _, monitor := range sc.monitors {
+		go monitor()
+	}
 
-	statusChan = sc.c.Start()
 	if sc.bkgd {
-		go sc.wait(statusChan)
+		go sc.wait(statusChan, expired)
 		return
 	}
 
-	sc.wait(statusChan)
+	sc.wait(statusChan, expired)
+}
+
+// awaitStart waits for the process to be running, which happens
+// asynchronously, and reports false if it never started
+func (sc *command) awaitStart() bool {
+	for sc.Result.PID() == 0 {
+		select {
+		case <-sc.c.Done():
+			return sc.Result.PID() != 0
+		case <-time.After(time.Millisecond):
+		}
+	}
+	return true
+}
+
+// beat calls fn at each interval until the command is done
+func (sc *command) beat(interval time.Duration, fn func(*Result)) {
+	for {
+		select {
+		case <-sc.done:
+			return
+		case <-sc.clock.After(interval):
+			fn(sc.Result)
+		}
+	}
+}
+
+// ------------------------------------------------------------------
+
+// prepare performs any setup that must happen just before launch
+func (sc *command) prepare() error {
+	if sc.err != nil {
+		return sc.err
+	}
+
+	for _, setup := range sc.setups {
+		if err := setup(); err != nil {
+			sc.cleanup()
+			return err
+		}
+	}
+
+	if len(sc.secrets) == 0 {
+		return nil
+	}
+
+	env := sc.env
+	if len(env) == 0 {
+		env = os.Environ()
+	}
+
+	for _, sec := range sc.secrets {
+		path, err := writeSecret(sec.value)
+		if err != nil {
+			sc.cleanup()
+			return fmt.Errorf("unable to pass secret %s: %v", sec.key, err)
+		}
+		sc.cleanups = append(sc.cleanups, func() { os.Remove(path) })
+		if sc.user != nil {
+			if err := os.Chown(path, int(sc.user.uid), int(sc.user.gid)); err != nil {
+				sc.cleanup()
+				return fmt.Errorf("unable to pass secret %s: %v", sec.key, err)
+			}
+		}
+		env = append(env, sec.key+"="+path)
+	}
+
+	sc.env = env
+	return nil
+}
+
+// cleanup releases resources held for the duration of the command
+func (sc *command) cleanup() {
+	for _, fn := range sc.cleanups {
+		fn()
+	}
+	sc.cleanups = nil
+}
+
+// fail marks the command as done without it ever having started
+func (sc *command) fail(err error) {
+	sc.cleanup()
+	sc.Result.final = &cmd.Status{
+		Cmd:   sc.exe,
+		Exit:  -1,
+		Error: err,
+	}
+	sc.logDone()
+	sc.traceDone()
+	close(sc.done)
+	sc.exited()
+}
+
+// writeSecret stores the value in a file only readable by the current
+// user, preferring a memory-backed filesystem, and returns its path
+func writeSecret(value string) (string, error) {
+	dir := os.TempDir()
+	if fi, err := os.Stat("/dev/shm"); err == nil && fi.IsDir() {
+		dir = "/dev/shm"
+	}
+
+	f, err := ioutil.TempFile(dir, "shell-secret-")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = f.WriteString(value)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
 }
 
 // ------------------------------------------------------------------
 
-func (sc *command) wait(statusChan <-chan cmd.Status) {
+func (sc *command) wait(statusChan <-chan cmd.Status, expired <-chan time.Time) {
 	select {
-	case final := <-statusChan:
-		// process is done; grab the final full output
-		sc.Result.final = &final
+	case <-statusChan:
+		// process is done; let the final status, and
+		// any output still in flight, be captured
+		<-sc.done
+	case <-expired:
+		sc.Result.timedOut = true
+		sc.kill()
 	case <-sc.stop:
 		sc.Result.canceled = true
 		sc.kill()
+	case <-sc.killed:
+		sc.Result.canceled = true
+		sc.kill()
 	case <-sc.ctx.Done():
 		err := sc.ctx.Err()
 		switch err {
@@ -263,9 +845,61 @@ func (sc *command) wait(statusChan <-chan cmd.Status) {
 
 // ------------------------------------------------------------------
 
-// Kill will terminate the internal cmd.Cmd
+// Kill will terminate the internal cmd.Cmd, or Backend Process,
+// forcibly so if it is still running once the KillGrace period
+// has elapsed
 func (sc *command) kill() {
+	sc.logKill()
+	sc.traceKill()
+	for _, fn := range sc.onKill {
+		fn(sc.killReason())
+	}
+
+	// a process still being launched would not be stopped,
+	// but a Backend's Process takes care of that itself
+	native := sc.backend == nil
+	if native && !sc.awaitStart() {
+		return
+	}
+
+	// descendants must be found while they are still attached to the
+	// tree, and those found before a grace period may since have exited
+	// and had their PIDs reused, so they are only found once it is over
+	pid := sc.Result.PID()
+	var tree []int
+	if native && sc.group && sc.grace <= 0 {
+		tree = descendants(pid)
+	}
+
 	sc.c.Stop()
+	if sc.grace > 0 {
+		select {
+		case <-sc.c.Done():
+			if !sc.group || !native {
+				return
+			}
+		case <-sc.clock.After(sc.grace):
+		}
+		if native && sc.group {
+			tree = descendants(pid)
+		}
+	} else if !sc.group {
+		return
+	}
+
+	if !native {
+		killProcess(sc.c)
+		return
+	}
+	if pid <= 0 {
+		return
+	}
+
+	if sc.group {
+		killTree(pid, tree)
+	} else {
+		forceKill(pid)
+	}
 }
 
 // ------------------------------------------------------------------
@@ -278,9 +912,14 @@ type Option func(s *command)
 // Context is an Option to set a context on the command
 // that will interrupt the command if the context is done.
 // Only the last call to this function will be taken into
-// account.
+// account. Any Options stored in the context by NewContext
+// are applied at this point, and so are overridden by
+// Options that come later.
 func Context(ctx context.Context) Option {
 	return func(s *command) {
+		for _, option := range optionsFromContext(ctx) {
+			option(s)
+		}
 		s.ctx = ctx
 	}
 }
@@ -296,6 +935,55 @@ func Timeout(d time.Duration) Option {
 	}
 }
 
+// KillGrace is an Option to give a command that is timed out or
+// canceled the given period to exit after being sent SIGTERM, before
+// it and its process group are sent SIGKILL. By default, commands
+// are only ever sent SIGTERM.
+func KillGrace(d time.Duration) Option {
+	return func(s *command) {
+		if d > 0 {
+			s.grace = d
+		}
+	}
+}
+
+// Heartbeat is an Option to call fn every interval while the command
+// runs, to keep proxies, SSH sessions or CI log watchers from giving
+// up on a long silent command. Combined with Interactive, fn may
+// write to Result.Stdin. Multiple calls to this function will be
+// taken into account.
+func Heartbeat(interval time.Duration, fn func(*Result)) Option {
+	return func(s *command) {
+		if interval > 0 && fn != nil {
+			s.monitors = append(s.monitors, func() { s.beat(interval, fn) })
+		}
+	}
+}
+
+// WithClock is an Option to replace the command's time source, used
+// to enforce the Timeout and kill grace period, pace heartbeats and
+// alerts, and read the times stamped by StampEnv and Interleave. It
+// exists mainly so that tests can drive time forward without real
+// sleeps.
+func WithClock(c Clock) Option {
+	return func(s *command) {
+		if c != nil {
+			s.clock = c
+		}
+	}
+}
+
+// WithBackend is an Option to execute the command with the given
+// Backend rather than as an operating system process. Options acting
+// on the process itself, such as Env or NoNetwork, are then ignored.
+// Output lines reported by the Backend are delivered to streams,
+// line callbacks and other output Options as they appear.
+func WithBackend(b Backend) Option {
+	return func(s *command) {
+		s.backend = b
+	}
+}
+
 // Env is an Option to modify the shell command's
 // execution environment. Multiple calls to this function
 // will be taken into account, with all values passed
@@ -318,6 +1006,106 @@ func Cancel(stop <-chan struct{}) func(*command) {
 	}
 }
 
+// HashOutput is an Option to compute a running digest of the
+// command's stdout as it is produced, available once the command
+// is done via Result.OutputDigest. The package implementing the
+// hash must be linked into the binary, e.g. by importing crypto/sha256.
+func HashOutput(h crypto.Hash) Option {
+	return func(s *command) {
+		if !h.Available() {
+			s.err = fmt.Errorf("HashOutput: hash function #%d is unavailable", h)
+			return
+		}
+		s.Result.digest = h.New()
+		s.stdout = append(s.stdout, s.Result.digest)
+	}
+}
+
+// RawOutput is an Option to keep the command's output exactly as
+// written, for commands writing binary data, available in full via
+// Result.StdoutBytes and StderrBytes as well as split into lines
+func RawOutput() Option {
+	return func(s *command) {
+		s.Result.stdoutBuf = newMemoryBuffer()
+		s.Result.stderrBuf = newMemoryBuffer()
+	}
+}
+
+// DiscardOutput is an Option to keep none of the command's output,
+// for commands run only for their exit code. Output is still passed
+// to any line callbacks, streams or other destinations requested.
+func DiscardOutput() Option {
+	return func(s *command) {
+		s.Result.stdoutBuf = discardBuffer{}
+		s.Result.stderrBuf = discardBuffer{}
+	}
+}
+
+// CompressOutput is an Option to hold the captured output
+// compressed in memory, decompressing it each time it is accessed.
+// This trades CPU for a much smaller footprint when many Results
+// of verbose commands are retained.
+func CompressOutput() Option {
+	return func(s *command) {
+		s.Result.stdoutBuf = newCompressedBuffer()
+		s.Result.stderrBuf = newCompressedBuffer()
+	}
+}
+
+// Stdin is an Option to feed the given reader to the command's
+// standard input. Only the last call to this function will be
+// taken into account.
+func Stdin(r io.Reader) Option {
+	return func(s *command) {
+		s.stdin = r
+	}
+}
+
+// StdinString is an Option to feed the given string
+// to the command's standard input
+func StdinString(input string) Option {
+	return Stdin(strings.NewReader(input))
+}
+
+// Interactive is an Option to connect the command's standard input
+// to a pipe, written to via Result.Stdin while the command runs.
+// It is intended for use with Bkgd, and replaces any Stdin Option.
+func Interactive() Option {
+	return func(s *command) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			s.err = fmt.Errorf("Interactive: %v", err)
+			return
+		}
+		s.stdin = r
+		s.Result.stdin = w
+		s.cleanups = append(s.cleanups, func() {
+			r.Close()
+			w.Close()
+		})
+	}
+}
+
+// Secret is an Option to hand a sensitive value to the command
+// without it appearing on the command line or in the environment.
+// The value is written to a private file (on tmpfs where available)
+// whose path is exported to the command as the environment variable
+// key. The file is removed once the command is done.
+func Secret(key, value string) Option {
+	return func(s *command) {
+		s.secrets = append(s.secrets, secret{key, value})
+	}
+}
+
+// Tag is an Option to attach labels to the command, for use in
+// identifying its Result later on. Multiple calls to this function
+// will be taken into account.
+func Tag(tags ...string) Option {
+	return func(s *command) {
+		s.Result.tags = append(s.Result.tags, tags...)
+	}
+}
+
 // Bkgd is an Option to make the command run in the background
 func Bkgd() func(*command) {
 	return func(s *command) {
@@ -1,8 +1,17 @@
 package shell
 
 import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"os"
+	"reflect"
+	"runtime"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -20,6 +29,65 @@ func TestTimeoutOption(t *testing.T) {
 	}
 }
 
+func TestKillGraceOption(t *testing.T) {
+	start := time.Now()
+	result := Run("trap '' TERM; sleep 5", Timeout(100*time.Millisecond), KillGrace(200*time.Millisecond))
+	<-result.Ready()
+	if !result.TimedOut() {
+		t.Error("Expected process to be marked timed out")
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the process to be killed after the grace period, took %v", elapsed)
+	}
+}
+
+func TestHeartbeatOption(t *testing.T) {
+	var beats int32
+	result := Run("sleep 0.3", Heartbeat(50*time.Millisecond, func(r *Result) {
+		atomic.AddInt32(&beats, 1)
+	}))
+	<-result.Ready()
+
+	n := atomic.LoadInt32(&beats)
+	if n < 2 {
+		t.Errorf("Expected several heartbeats, got %d", n)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if atomic.LoadInt32(&beats) != n {
+		t.Error("Expected no heartbeats once the command is done")
+	}
+}
+
+// manualClock is a Clock whose timers only fire when told to
+type manualClock struct {
+	fire chan time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	return time.Time{}
+}
+
+func (c *manualClock) After(d time.Duration) <-chan time.Time {
+	return c.fire
+}
+
+func TestClockOption(t *testing.T) {
+	clock := &manualClock{fire: make(chan time.Time, 1)}
+	clock.fire <- time.Time{}
+
+	start := time.Now()
+	result := Run("sleep 5", Timeout(time.Hour), WithClock(clock))
+	if !result.TimedOut() {
+		t.Error("Expected process to be marked timed out")
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the injected clock to expire the timeout, took %v", elapsed)
+	}
+}
+
 func TestEnvOption(t *testing.T) {
 	before := Run("env")
 	after := Run("env", Env([]string{"HIP_HIP=hooray"}))
@@ -44,21 +112,300 @@ func TestCancelOption(t *testing.T) {
 	}
 }
 
+func TestSecretOption(t *testing.T) {
+	result := Run(`cat "$DB_PASSWORD"; echo; echo "$DB_PASSWORD"`, Secret("DB_PASSWORD", "s3cr3t"))
+	lines := result.Stdout().Lines()
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 output lines, got %d: %v", len(lines), lines)
+	}
+
+	if lines[0] != "s3cr3t" {
+		t.Errorf("Expected secret file to contain the value, got %q", lines[0])
+	}
+
+	<-result.Ready()
+	if _, err := os.Stat(lines[1]); !os.IsNotExist(err) {
+		t.Errorf("Expected secret file %s to be removed", lines[1])
+	}
+}
+
+func TestNoNetworkOption(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		result := Run("true", NoNetwork())
+		if !errors.Is(result.Err(), ErrUnsupported) {
+			t.Errorf("Expected ErrUnsupported, got %v", result.Err())
+		}
+		return
+	}
+
+	result := Run("tail -n +3 /proc/net/dev | cut -d: -f1", NoNetwork())
+	if result.IsError() {
+		t.Skipf("Unable to create network namespace: %v", result.Err())
+	}
+
+	for _, iface := range result.Stdout().Lines() {
+		if strings.TrimSpace(iface) != "lo" {
+			t.Errorf("Expected only the loopback device, found %q", iface)
+		}
+	}
+}
+
+func TestHashOutputOption(t *testing.T) {
+	result := Run("printf 'hello\nworld\n'", HashOutput(crypto.SHA256))
+	<-result.Ready()
+
+	want := sha256.Sum256([]byte("hello\nworld\n"))
+	if got := result.OutputDigest(); !bytes.Equal(got, want[:]) {
+		t.Errorf("Expected digest %x, got %x", want, got)
+	}
+
+	if result.Stdout().Text() != "hello\nworld" {
+		t.Error("Expected output to still be captured")
+	}
+}
+
+func TestCompressOutputOption(t *testing.T) {
+	result := Run("seq 1 1000; echo oops >&2", CompressOutput())
+	<-result.Ready()
+
+	lines := result.Stdout().Lines()
+	if len(lines) != 1000 || lines[0] != "1" || lines[999] != "1000" {
+		t.Errorf("Unexpected stdout: %d lines", len(lines))
+	}
+
+	if got := result.Stderr().Text(); got != "oops" {
+		t.Errorf("Unexpected stderr: %q", got)
+	}
+
+	if !result.Stdout().Empty() {
+		t.Error("Expected no further stdout lines")
+	}
+}
+
+func TestPartialLineWhileRunning(t *testing.T) {
+	buffers := map[string][]Option{
+		"CompressOutput": {CompressOutput()},
+		"RawOutput":      {RawOutput()},
+		"MaxOutputLines": {MaxOutputLines(10)},
+	}
+	for name, options := range buffers {
+		result := Run(`printf 'hel'; sleep 0.5; printf 'lo\nworld\n'`, append(options, Bkgd())...)
+		time.Sleep(200 * time.Millisecond)
+
+		var lines []string
+		lines = append(lines, result.Stdout().Lines()...)
+		<-result.Ready()
+		lines = append(lines, result.Stdout().Lines()...)
+
+		if !reflect.DeepEqual(lines, []string{"hello", "world"}) {
+			t.Errorf("%s: expected an incomplete line to be held back, got %q", name, lines)
+		}
+	}
+}
+
+func TestRawOutputOption(t *testing.T) {
+	result := Run(`printf 'a\r\nb\0\xff'; printf 'e\n' >&2`, RawOutput())
+	<-result.Ready()
+
+	if got := result.StdoutBytes(); !bytes.Equal(got, []byte("a\r\nb\x00\xff")) {
+		t.Errorf("Expected stdout byte for byte, got %q", got)
+	}
+	if got := result.StderrBytes(); !bytes.Equal(got, []byte("e\n")) {
+		t.Errorf("Expected stderr byte for byte, got %q", got)
+	}
+	if lines := result.FullStdout().Lines(); len(lines) != 2 || lines[0] != "a" {
+		t.Errorf("Expected the output to still be split into lines, got %q", lines)
+	}
+
+	if got := Run("echo hi").StdoutBytes(); got != nil {
+		t.Errorf("Expected no bytes without the Option, got %q", got)
+	}
+}
+
+func TestDiscardOutputOption(t *testing.T) {
+	result := Run("seq 1 1000; echo oops >&2; exit 3", DiscardOutput())
+	if result.ExitCode() != 3 {
+		t.Errorf("Expected exit code 3, got %d", result.ExitCode())
+	}
+	if !result.FullStdout().Empty() || !result.FullStderr().Empty() {
+		t.Error("Expected no output to be kept")
+	}
+
+	var lines int32
+	result = Run("seq 1 10", DiscardOutput(), OnStdoutLine(func(string) { atomic.AddInt32(&lines, 1) }))
+	if n := atomic.LoadInt32(&lines); n != 10 || !result.FullStdout().Empty() {
+		t.Errorf("Expected the callback to see 10 lines and none to be kept, got %d", n)
+	}
+
+	if runtime.GOOS == "linux" {
+		result = Run(`[ "$(readlink /proc/$$/fd/1)" = /dev/null ]`, DiscardOutput())
+		if result.ExitCode() != 0 {
+			t.Error("Expected the output to be sent to /dev/null")
+		}
+	}
+}
+
+func TestInterleaveOption(t *testing.T) {
+	result := Run("echo one; sleep 0.1; echo two >&2; sleep 0.1; printf three", Interleave())
+	<-result.Ready()
+
+	lines := strings.Split(strings.TrimSuffix(result.Transcript(), "\n"), "\n")
+	want := []string{"[out]", "one", "[err]", "two", "[out]", "three"}
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 transcript lines, got %q", lines)
+	}
+
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[0] != want[2*i] || fields[2] != want[2*i+1] {
+			t.Errorf("Unexpected transcript line %d: %q", i, line)
+		}
+	}
+}
+
+func TestStdinOption(t *testing.T) {
+	res := Run("grep -c needle", Stdin(strings.NewReader("hay\nneedle\nhay\nneedle\n")))
+	if got := res.Stdout().Text(); got != "2" {
+		t.Errorf("Expected 2 matching lines, got %q", got)
+	}
+
+	res = Run("tr a-z A-Z", StdinString("shout"))
+	if got := res.Stdout().Text(); got != "SHOUT" {
+		t.Errorf("Unexpected output: %q", got)
+	}
+}
+
+func TestInteractiveOption(t *testing.T) {
+	res := Run("while read line; do echo \"got $line\"; done", Interactive(), Bkgd())
+	if res.Stdin() == nil {
+		t.Fatal("Expected a stdin writer")
+	}
+
+	fmt.Fprintln(res.Stdin(), "one")
+	fmt.Fprintln(res.Stdin(), "two")
+	if res.IsReady() {
+		t.Error("Command should still be waiting for input")
+	}
+
+	res.Stdin().Close()
+	<-res.Ready()
+	if got := res.FullStdout().Text(); got != "got one\ngot two" {
+		t.Errorf("Unexpected output: %q", got)
+	}
+
+	if Run("true").Stdin() != nil {
+		t.Error("Expected no stdin writer without Interactive")
+	}
+}
+
+func TestCombined(t *testing.T) {
+	result := Run("echo one; sleep 0.1; echo two >&2; sleep 0.1; echo three", Interleave())
+	<-result.Ready()
+
+	lines := result.Combined()
+	want := []Line{{Source: Stdout, Text: "one"}, {Source: Stderr, Text: "two"}, {Source: Stdout, Text: "three"}}
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d lines, got %v", len(want), lines)
+	}
+
+	for i, line := range lines {
+		if line.Source != want[i].Source || line.Text != want[i].Text {
+			t.Errorf("Line %d: expected %v %q, got %v %q", i, want[i].Source, want[i].Text, line.Source, line.Text)
+		}
+		if i > 0 && line.Time.Before(lines[i-1].Time) {
+			t.Errorf("Line %d arrived before the previous one", i)
+		}
+	}
+
+	if Run("true").Combined() != nil {
+		t.Error("Expected no combined output without Interleave")
+	}
+}
+
 func TestBkgdOption(t *testing.T) {
 	res := Run("echo 'hello';sleep 1;echo 'world';", Bkgd())
 	if res.IsReady() {
 		t.Error("Background process should still be running")
 	}
 
-	for !res.IsReady() {
-		t.Log("Process is bkgd'ed, waiting...")
-		t.Log(res.Stdout().Text())
-		t.Log(res.Stderr().Text())
-		<-time.After(500 * time.Millisecond)
+	if _, err := res.Wait(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if lines := res.FullStdout().Lines(); len(lines) != 2 || lines[1] != "world" {
+		t.Errorf("Expected all the output once done, got %q", lines)
+	}
+}
+
+func TestWaitContext(t *testing.T) {
+	stop := make(chan struct{})
+	res := Run("sleep 5", Bkgd(), Cancel(stop))
+	defer close(stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := res.WaitContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected the wait to time out, got %v", err)
+	}
+	if res.IsReady() {
+		t.Error("Expected the command to be left running")
+	}
+
+	r, err := Run("exit 3", Bkgd(), FailOnNonZero()).WaitContext(context.Background())
+	if r.ExitCode() != 3 || err == nil {
+		t.Errorf("Expected the Result and its error, got %v, %v", r, err)
+	}
+}
+
+func TestKill(t *testing.T) {
+	start := time.Now()
+	res := Run("sleep 5", Bkgd())
+	res.Kill()
+
+	if !res.IsReady() || !errors.Is(res.Err(), ErrCanceled) {
+		t.Errorf("Expected the command to be done and canceled, got %v", res)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the command to be stopped promptly, took %v", elapsed)
+	}
+
+	// killing again, or a command already done, is harmless
+	res.Kill()
+	done := Run("true")
+	done.Kill()
+	if done.Err() != nil {
+		t.Errorf("Expected a finished command to be unaffected, got %v", done.Err())
+	}
+}
+
+func TestRunWithArgs(t *testing.T) {
+	args := []string{"a b", "$(echo injected)", "c; echo d", "'"}
+	res := RunWithArgs(`printf '%s\n' "$@"`, args)
+
+	got := res.Stdout().Lines()
+	if strings.Join(got, "|") != strings.Join(args, "|") {
+		t.Errorf("Expected arguments passed verbatim, got %q", got)
+	}
+}
+
+func TestRunArgs(t *testing.T) {
+	res := RunArgs("echo", []string{"$HOME", "a;b", "*"})
+	if got := res.Stdout().Text(); got != "$HOME a;b *" {
+		t.Errorf("Expected arguments passed verbatim, got %q", got)
+	}
+
+	res = RunArgs("no-such-program-exists", nil)
+	if !res.IsError() {
+		t.Error("Expected an error running a missing program")
 	}
-	t.Log(res.Stdout().Text())
-	t.Log(res.Stderr().Text())
+}
+
+func TestRunStdinScript(t *testing.T) {
+	script := "x='it''s \"quoted\"'\necho \"$x\" $((6*7))\n"
+	res := RunStdinScript("bash -s", script)
 
+	if got := res.Stdout().Text(); got != `its "quoted" 42` {
+		t.Errorf("Unexpected output: %q", got)
+	}
 }
 
 func TestStdOutputs(t *testing.T) {
@@ -134,6 +481,42 @@ func TestErrorValues(t *testing.T) {
 	}
 }
 
+func TestTerminationErrors(t *testing.T) {
+	stop := make(chan struct{})
+	close(stop)
+
+	tests := []struct {
+		name   string
+		result *Result
+		expect error
+	}{
+		{"timing out", Run("sleep 5", Timeout(100*time.Millisecond)), ErrTimeout},
+		{"canceling", Run("sleep 5", Cancel(stop)), ErrCanceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			<-tt.result.Ready()
+			err := tt.result.Err()
+			if !errors.Is(err, tt.expect) {
+				t.Fatalf("Expected %v, got %v", tt.expect, err)
+			}
+
+			var terr *TerminationError
+			if !errors.As(err, &terr) || terr.Err == nil {
+				t.Errorf("Expected the signal to be wrapped, got %#v", err)
+			}
+			if errors.Is(err, ErrCrashed) {
+				t.Errorf("Expected only %v to match", tt.expect)
+			}
+		})
+	}
+
+	if err := Run("exit 1").Err(); err != nil {
+		t.Errorf("Expected no error for a non-zero exit, got %v", err)
+	}
+}
+
 func TestExitCodes(t *testing.T) {
 	tests := []struct {
 		name   string
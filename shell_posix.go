@@ -0,0 +1,67 @@
+//go:build !windows
+// +build !windows
+
+package shell
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// shellExe is the shell used to run command strings
+const shellExe = "/bin/bash"
+
+// newShellCommand creates a command running the script with bash
+func newShellCommand(script string, options ...Option) *command {
+	return newCommand(shellExe, []string{"-c", script}, options...)
+}
+
+// newShellCommandWithArgs creates a command running the script
+// with bash, with args as its positional parameters
+func newShellCommandWithArgs(script string, args []string, options ...Option) *command {
+	// the first argument after the script is $0
+	bashArgs := append([]string{"-c", script, shellExe}, args...)
+	return newCommand(shellExe, bashArgs, options...)
+}
+
+// forceKill sends SIGKILL to the process group led by pid
+func forceKill(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGKILL)
+}
+
+// signalGroup sends sig to the process group led by pid
+func signalGroup(pid int, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return fmt.Errorf("unsupported signal %v", sig)
+	}
+	return syscall.Kill(-pid, s)
+}
+
+// killTree sends SIGKILL to the process group led by pid,
+// and to each of the given processes
+func killTree(pid int, pids []int) error {
+	err := forceKill(pid)
+	if err == syscall.ESRCH {
+		err = nil
+	}
+
+	for _, p := range pids {
+		if kerr := syscall.Kill(p, syscall.SIGKILL); kerr != nil && kerr != syscall.ESRCH && err == nil {
+			err = kerr
+		}
+	}
+	return err
+}
+
+// stopDescendants sends SIGTERM to all the descendants of pid
+// that can be found, leaving pid itself running
+func stopDescendants(pid int) error {
+	for _, p := range descendants(pid) {
+		if err := syscall.Kill(p, syscall.SIGTERM); err != nil && err != syscall.ESRCH {
+			return err
+		}
+	}
+	return nil
+}
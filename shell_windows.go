@@ -0,0 +1,66 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// shellExe is the shell used to run command strings
+const shellExe = "cmd.exe"
+
+// newShellCommand creates a command running the script with cmd.exe
+func newShellCommand(script string, options ...Option) *command {
+	s := newCommand(shellExe, []string{"/S", "/C", script}, options...)
+
+	// cmd.exe does not follow the usual argument quoting rules,
+	// so hand it the command line exactly as it should see it
+	s.beforeExec = append(s.beforeExec, func(c *exec.Cmd) {
+		if c.SysProcAttr == nil {
+			c.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		c.SysProcAttr.CmdLine = fmt.Sprintf(`%s /S /C "%s"`, shellExe, script)
+	})
+	return s
+}
+
+// newShellCommandWithArgs is not supported: cmd.exe has no
+// positional parameters to pass the args through safely
+func newShellCommandWithArgs(script string, args []string, options ...Option) *command {
+	s := newCommand(shellExe, nil, options...)
+	s.err = fmt.Errorf("RunWithArgs: %w", ErrUnsupported)
+	return s
+}
+
+// forceKill terminates the process. Windows has no SIGTERM,
+// so the process will usually already have been killed by Stop.
+func forceKill(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
+
+// signalGroup sends sig to the process, Windows having no process
+// groups to signal. Only os.Kill is supported.
+func signalGroup(pid int, sig os.Signal) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Signal(sig)
+}
+
+// killTree terminates the process and all of its children. Windows
+// tracks the tree itself, so the given processes are not needed.
+func killTree(pid int, pids []int) error {
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(pid)).Run()
+}
+
+// stopDescendants is not supported, as sessions are not
+func stopDescendants(pid int) error {
+	return ErrUnsupported
+}
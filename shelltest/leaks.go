@@ -0,0 +1,34 @@
+package shelltest
+
+import (
+	"testing"
+	"time"
+)
+
+// leakSettle is how long CheckLeaks waits for resources to be released
+const leakSettle = 2 * time.Second
+
+// CheckLeaks snapshots the goroutines, file descriptors and child
+// processes held by the test binary, and returns a function that
+// fails the test if more are held when it is called. Typical use:
+//
+//	defer shelltest.CheckLeaks(t)()
+func CheckLeaks(t testing.TB) func() {
+	t.Helper()
+	before := CurrentUsage()
+
+	return func() {
+		t.Helper()
+
+		deadline := time.Now().Add(leakSettle)
+		after := CurrentUsage()
+		for after.exceeds(before) && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+			after = CurrentUsage()
+		}
+
+		if after.exceeds(before) {
+			t.Errorf("resources leaked: before %+v, after %+v", before, after)
+		}
+	}
+}
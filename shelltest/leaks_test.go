@@ -0,0 +1,19 @@
+package shelltest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brinick/shell"
+	"github.com/brinick/shell/shelltest"
+)
+
+func TestCheckLeaks(t *testing.T) {
+	defer shelltest.CheckLeaks(t)()
+
+	res := shell.Run("sleep 5", shell.Bkgd(), shell.Timeout(50*time.Millisecond))
+	<-res.Ready()
+	if !res.TimedOut() {
+		t.Error("Expected process to be marked timed out")
+	}
+}
@@ -0,0 +1,252 @@
+// Package shelltest provides helpers for testing code built on the
+// shell package, without needing to launch real processes.
+package shelltest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brinick/shell"
+	"github.com/go-cmd/cmd"
+)
+
+// ------------------------------------------------------------------
+
+// Step is a single scripted behaviour of a fake process
+type Step func(p *process)
+
+// Stdout is a Step that emits the given lines on stdout
+func Stdout(lines ...string) Step {
+	return func(p *process) {
+		p.emit(&p.status.Stdout, lines)
+	}
+}
+
+// Stderr is a Step that emits the given lines on stderr
+func Stderr(lines ...string) Step {
+	return func(p *process) {
+		p.emit(&p.status.Stderr, lines)
+	}
+}
+
+// Lines is a Step that emits n numbered lines on stdout
+func Lines(n int) Step {
+	return func(p *process) {
+		lines := make([]string, n)
+		for i := range lines {
+			lines[i] = fmt.Sprintf("line %d", i+1)
+		}
+		p.emit(&p.status.Stdout, lines)
+	}
+}
+
+// Sleep is a Step that pauses the script, unless the process is
+// stopped. Time is kept by the command's Clock, as set by WithClock.
+func Sleep(d time.Duration) Step {
+	return func(p *process) {
+		select {
+		case <-p.clock.After(d):
+		case <-p.stopped:
+		}
+	}
+}
+
+// Exit is a Step that ends the script with the given exit code
+func Exit(code int) Step {
+	return func(p *process) {
+		p.mu.Lock()
+		p.status.Exit = code
+		p.mu.Unlock()
+		p.exited = true
+	}
+}
+
+// IgnoreTerm is a Step after which the process no longer reacts
+// to Stop, as a process ignoring SIGTERM, though it can still be killed
+func IgnoreTerm() Step {
+	return func(p *process) {
+		p.mu.Lock()
+		p.ignoreTerm = true
+		p.mu.Unlock()
+	}
+}
+
+// ------------------------------------------------------------------
+
+// Script returns a shell.Backend whose processes play out the given
+// steps in order, exiting with code 0 unless an Exit step says otherwise.
+func Script(steps ...Step) shell.Backend {
+	return func(name string, args []string) shell.Process {
+		return &process{
+			steps:   steps,
+			clock:   systemClock{},
+			stopped: make(chan struct{}),
+			done:    make(chan struct{}),
+			status: cmd.Status{
+				Cmd:  strings.Join(append([]string{name}, args...), " "),
+				Exit: -1,
+			},
+		}
+	}
+}
+
+// ------------------------------------------------------------------
+
+// nextPID hands out fake, but distinct, process ids
+var nextPID = struct {
+	sync.Mutex
+	pid int
+}{pid: 100000}
+
+// systemClock is the Clock of processes run without WithClock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// process is the fake shell.Process that executes a script
+type process struct {
+	steps []Step
+	clock shell.Clock
+
+	mu         sync.Mutex
+	status     cmd.Status
+	statusChan chan cmd.Status
+	started    time.Time
+	ignoreTerm bool
+	exited     bool
+	halted     bool
+	killed     bool
+	stopped    chan struct{}
+	done       chan struct{}
+}
+
+func (p *process) Start() <-chan cmd.Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.statusChan != nil {
+		return p.statusChan
+	}
+
+	nextPID.Lock()
+	nextPID.pid++
+	p.status.PID = nextPID.pid
+	nextPID.Unlock()
+
+	p.started = p.clock.Now()
+	p.status.StartTs = p.started.UnixNano()
+	p.statusChan = make(chan cmd.Status, 1)
+	go p.run()
+	return p.statusChan
+}
+
+func (p *process) Stop() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.statusChan == nil {
+		return cmd.ErrNotStarted
+	}
+
+	if p.ignoreTerm || p.halted {
+		return nil
+	}
+
+	p.halted = true
+	close(p.stopped)
+	return nil
+}
+
+// Kill stops the process even if it ignores Stop
+func (p *process) Kill() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.statusChan == nil {
+		return cmd.ErrNotStarted
+	}
+
+	p.killed = true
+	if !p.halted {
+		p.halted = true
+		close(p.stopped)
+	}
+	return nil
+}
+
+// UseClock sets the Clock keeping the script's time
+func (p *process) UseClock(c shell.Clock) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clock = c
+}
+
+func (p *process) Status() cmd.Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	st := p.status
+	st.Stdout = append([]string{}, p.status.Stdout...)
+	st.Stderr = append([]string{}, p.status.Stderr...)
+	if !p.started.IsZero() && st.StopTs == 0 {
+		st.Runtime = p.clock.Now().Sub(p.started).Seconds()
+	}
+	return st
+}
+
+func (p *process) Done() <-chan struct{} {
+	return p.done
+}
+
+// run plays the script, stopping early if the process is stopped
+func (p *process) run() {
+	for _, step := range p.steps {
+		if p.exited || p.isHalted() {
+			break
+		}
+		step(p)
+	}
+
+	p.mu.Lock()
+	now := p.clock.Now()
+	switch {
+	case p.killed:
+		p.status.Exit = -1
+		p.status.Error = errors.New("signal: killed")
+	case p.halted:
+		p.status.Exit = -1
+		p.status.Error = errors.New("signal: terminated")
+	default:
+		p.status.Complete = true
+		if !p.exited {
+			p.status.Exit = 0
+		}
+	}
+	p.status.StopTs = now.UnixNano()
+	p.status.Runtime = now.Sub(p.started).Seconds()
+	p.mu.Unlock()
+
+	p.statusChan <- p.Status()
+	close(p.done)
+}
+
+func (p *process) isHalted() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.halted
+}
+
+func (p *process) emit(out *[]string, lines []string) {
+	p.mu.Lock()
+	*out = append(*out, lines...)
+	p.mu.Unlock()
+}
@@ -0,0 +1,190 @@
+package shelltest_test
+
+import (
+	"crypto"
+	_ "crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/brinick/shell"
+	"github.com/brinick/shell/shelltest"
+)
+
+func TestScriptOutputAndExit(t *testing.T) {
+	backend := shelltest.Script(
+		shelltest.Lines(3),
+		shelltest.Stderr("oops"),
+		shelltest.Exit(2),
+		shelltest.Stdout("never printed"),
+	)
+
+	res := shell.Run("anything", shell.WithBackend(backend))
+	if got := res.Stdout().Lines(); len(got) != 3 || got[2] != "line 3" {
+		t.Errorf("Unexpected stdout: %v", got)
+	}
+
+	if got := res.Stderr().Text(); got != "oops" {
+		t.Errorf("Unexpected stderr: %q", got)
+	}
+
+	if res.ExitCode() != 2 {
+		t.Errorf("Expected exit code 2, got %d", res.ExitCode())
+	}
+}
+
+func TestScriptStream(t *testing.T) {
+	backend := shelltest.Script(
+		shelltest.Stdout("one"),
+		shelltest.Sleep(30*time.Millisecond),
+		shelltest.Stdout("two"),
+		shelltest.Stderr("oops"),
+	)
+
+	res := shell.Run("anything", shell.WithBackend(backend), shell.Stream(), shell.Bkgd())
+
+	var stdout, stderr []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for line := range res.StderrStream() {
+			stderr = append(stderr, line)
+		}
+	}()
+	for line := range res.StdoutStream() {
+		stdout = append(stdout, line)
+	}
+	<-done
+
+	if len(stdout) != 2 || stdout[0] != "one" || stdout[1] != "two" {
+		t.Errorf("Unexpected streamed stdout: %v", stdout)
+	}
+	if len(stderr) != 1 || stderr[0] != "oops" {
+		t.Errorf("Unexpected streamed stderr: %v", stderr)
+	}
+	if got := res.Stdout().Lines(); len(got) != 2 {
+		t.Errorf("Expected stdout to be captured as well, got %v", got)
+	}
+}
+
+func TestScriptLineCallbacks(t *testing.T) {
+	backend := shelltest.Script(
+		shelltest.Lines(2),
+		shelltest.Stderr("warn 1", "warn 2"),
+	)
+
+	var stdout, stderr []string
+	alerted := 0
+	res := shell.Run("anything",
+		shell.WithBackend(backend),
+		shell.OnStdoutLine(func(line string) { stdout = append(stdout, line) }),
+		shell.OnStderrLine(func(line string) { stderr = append(stderr, line) }),
+		shell.StderrLinesAlert(1, func(*shell.Result) { alerted++ }),
+		shell.HashOutput(crypto.SHA256),
+		shell.Interleave(),
+	)
+
+	if len(stdout) != 2 || stdout[1] != "line 2" {
+		t.Errorf("Unexpected stdout lines: %v", stdout)
+	}
+	if len(stderr) != 2 || stderr[0] != "warn 1" {
+		t.Errorf("Unexpected stderr lines: %v", stderr)
+	}
+	if alerted != 1 {
+		t.Errorf("Expected the stderr alert to fire once, got %d", alerted)
+	}
+
+	h := crypto.SHA256.New()
+	h.Write([]byte("line 1\nline 2\n"))
+	if got, want := fmt.Sprintf("%x", res.OutputDigest()), fmt.Sprintf("%x", h.Sum(nil)); got != want {
+		t.Errorf("Unexpected output digest %s, want %s", got, want)
+	}
+	if got := res.Combined(); len(got) != 4 {
+		t.Errorf("Expected both streams in the transcript, got %v", got)
+	}
+}
+
+func TestScriptTimeout(t *testing.T) {
+	backend := shelltest.Script(shelltest.Sleep(time.Hour))
+	res := shell.Run("anything", shell.WithBackend(backend), shell.Timeout(10*time.Millisecond))
+	if !res.TimedOut() {
+		t.Error("Expected process to be marked timed out")
+	}
+
+	select {
+	case <-res.Ready():
+	case <-time.After(time.Second):
+		t.Error("Expected stopped process to finish")
+	}
+}
+
+func TestScriptIgnoreTerm(t *testing.T) {
+	backend := shelltest.Script(shelltest.IgnoreTerm(), shelltest.Sleep(50*time.Millisecond))
+	res := shell.Run("anything", shell.WithBackend(backend), shell.Timeout(time.Millisecond))
+	if !res.TimedOut() {
+		t.Error("Expected process to be marked timed out")
+	}
+
+	<-res.Ready()
+	if res.ExitCode() != 0 {
+		t.Errorf("Expected process ignoring the stop to run to completion, got exit code %d", res.ExitCode())
+	}
+}
+
+func TestScriptKillGrace(t *testing.T) {
+	backend := shelltest.Script(shelltest.IgnoreTerm(), shelltest.Sleep(time.Hour))
+
+	start := time.Now()
+	res := shell.Run("anything", shell.WithBackend(backend),
+		shell.Timeout(10*time.Millisecond), shell.KillGrace(50*time.Millisecond))
+
+	select {
+	case <-res.Ready():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the process ignoring the stop to be killed once the grace period elapsed")
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected the grace period to be honoured, took %v", elapsed)
+	}
+	if !res.TimedOut() || res.ExitCode() != -1 {
+		t.Errorf("Expected a timed out, killed process: %v", res)
+	}
+}
+
+func TestScriptProcessGroup(t *testing.T) {
+	backend := shelltest.Script(shelltest.IgnoreTerm(), shelltest.Sleep(time.Hour))
+	res := shell.Run("anything", shell.WithBackend(backend),
+		shell.Timeout(10*time.Millisecond), shell.ProcessGroup())
+
+	select {
+	case <-res.Ready():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the process group to be killed straight away")
+	}
+}
+
+// instantClock is a Clock under which every wait is over at once
+type instantClock struct{}
+
+func (instantClock) Now() time.Time {
+	return time.Unix(0, 0)
+}
+
+func (instantClock) After(d time.Duration) <-chan time.Time {
+	c := make(chan time.Time, 1)
+	c <- time.Unix(0, 0).Add(d)
+	return c
+}
+
+func TestScriptClock(t *testing.T) {
+	backend := shelltest.Script(shelltest.Sleep(time.Hour), shelltest.Stdout("awake"))
+
+	start := time.Now()
+	res := shell.Run("anything", shell.WithBackend(backend), shell.WithClock(instantClock{}))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the script to sleep by the injected clock, took %v", elapsed)
+	}
+	if res.Stdout().Text() != "awake" || res.ExitCode() != 0 {
+		t.Errorf("Expected the script to run to completion: %v", res)
+	}
+}
@@ -0,0 +1,180 @@
+package shelltest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brinick/shell"
+)
+
+// SoakConfig describes a soak run
+type SoakConfig struct {
+	// Command is the command to run repeatedly (default "true")
+	Command string
+
+	// Options are applied to every run of the command
+	Options []shell.Option
+
+	// Runs is the total number of times to run the command (default 1000)
+	Runs int
+
+	// Concurrency is the number of goroutines sharing the runs (default 8)
+	Concurrency int
+
+	// Settle is how long to allow, once all runs are done,
+	// for resources to be released (default 2s)
+	Settle time.Duration
+}
+
+// SoakReport summarises a soak run. Resource counts are -1
+// where they cannot be measured on the current platform.
+type SoakReport struct {
+	Runs     int
+	Failures int
+	Duration time.Duration
+
+	Before Usage
+	After  Usage
+}
+
+// Usage is a snapshot of the resources held by the current process
+type Usage struct {
+	Goroutines int
+	FDs        int
+	Children   int
+}
+
+// Soak runs the configured command many times over concurrently,
+// and returns an error if, once the runs are done and the settle
+// period has elapsed, more goroutines, file descriptors or child
+// processes are held than before the runs started.
+func Soak(cfg SoakConfig) (*SoakReport, error) {
+	if cfg.Command == "" {
+		cfg.Command = "true"
+	}
+	if cfg.Runs <= 0 {
+		cfg.Runs = 1000
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 8
+	}
+	if cfg.Settle <= 0 {
+		cfg.Settle = 2 * time.Second
+	}
+
+	report := &SoakReport{
+		Runs:   cfg.Runs,
+		Before: CurrentUsage(),
+	}
+
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		jobs  = make(chan struct{})
+		start = time.Now()
+	)
+
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				res := shell.Run(cfg.Command, cfg.Options...)
+				<-res.Ready()
+				if res.IsError() || res.ExitCode() != 0 {
+					mu.Lock()
+					report.Failures++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < cfg.Runs; i++ {
+		jobs <- struct{}{}
+	}
+	close(jobs)
+	wg.Wait()
+	report.Duration = time.Since(start)
+
+	deadline := time.Now().Add(cfg.Settle)
+	for {
+		report.After = CurrentUsage()
+		if !report.After.exceeds(report.Before) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if report.After.exceeds(report.Before) {
+		return report, fmt.Errorf(
+			"resources leaked over %d runs: before %+v, after %+v",
+			report.Runs,
+			report.Before,
+			report.After,
+		)
+	}
+
+	return report, nil
+}
+
+// ------------------------------------------------------------------
+
+// CurrentUsage returns a snapshot of the resources held by the current process
+func CurrentUsage() Usage {
+	return Usage{
+		Goroutines: runtime.NumGoroutine(),
+		FDs:        countFDs(),
+		Children:   countChildren(),
+	}
+}
+
+// exceeds indicates if any measurable resource count in u is above that in o
+func (u Usage) exceeds(o Usage) bool {
+	return u.Goroutines > o.Goroutines ||
+		(u.FDs >= 0 && o.FDs >= 0 && u.FDs > o.FDs) ||
+		(u.Children >= 0 && o.Children >= 0 && u.Children > o.Children)
+}
+
+// countFDs returns the number of open file descriptors, or -1 if unknown
+func countFDs() int {
+	entries, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
+
+// countChildren returns the number of child processes, or -1 if unknown
+func countChildren() int {
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return -1
+	}
+
+	ppid := strconv.Itoa(os.Getpid())
+	n := 0
+	for _, e := range entries {
+		if _, err := strconv.Atoi(e.Name()); err != nil {
+			continue
+		}
+
+		stat, err := ioutil.ReadFile("/proc/" + e.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+
+		// the command name is in parentheses and may contain spaces
+		fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+		if len(fields) > 1 && fields[1] == ppid {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,23 @@
+package shelltest_test
+
+import (
+	"testing"
+
+	"github.com/brinick/shell"
+	"github.com/brinick/shell/shelltest"
+)
+
+func TestSoak(t *testing.T) {
+	report, err := shelltest.Soak(shelltest.SoakConfig{
+		Command: "echo hello",
+		Options: []shell.Option{shell.Bkgd()},
+		Runs:    200,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Failures != 0 {
+		t.Errorf("Expected no failures, got %d", report.Failures)
+	}
+}
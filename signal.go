@@ -0,0 +1,25 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Signal sends sig to the command's process group, reaching the
+// command and any children it started, e.g. syscall.SIGHUP to have a
+// daemon reload its configuration. Only os.Kill is supported on
+// Windows, where only the command itself is signalled.
+func (r *Result) Signal(sig os.Signal) error {
+	pid := r.PID()
+	if pid <= 0 {
+		return errors.New("Signal: command has no process")
+	}
+	if r.IsReady() {
+		return errors.New("Signal: command is done")
+	}
+	if err := signalGroup(pid, sig); err != nil {
+		return fmt.Errorf("Signal: %v", err)
+	}
+	return nil
+}
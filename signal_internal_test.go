@@ -0,0 +1,40 @@
+//go:build !windows
+// +build !windows
+
+package shell
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignal(t *testing.T) {
+	res := Run(`trap 'echo reloaded' HUP; echo ready; while :; do sleep 0.05; done`, Bkgd())
+	defer res.Kill()
+
+	for !strings.Contains(res.FullStdout().Text(), "ready") {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := res.Signal(syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(res.FullStdout().Text(), "reloaded") {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the command to handle the signal, got %q", res.FullStdout().Lines())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if res.IsReady() {
+		t.Error("Expected the command to keep running")
+	}
+
+	res.Kill()
+	if err := res.Signal(syscall.SIGHUP); err == nil {
+		t.Error("Expected signalling a finished command to fail")
+	}
+}
@@ -0,0 +1,48 @@
+package shell
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StampEnv is an Option to add variables describing the run to the
+// command's environment, so that its output can be correlated with
+// the process managing it. Given the prefix "JOB", it sets:
+//
+//	JOB_RUN_ID      a random identifier, also given by Result.RunID
+//	JOB_PARENT_PID  the pid of the current process
+//	JOB_START_TIME  the time of launch by the command's Clock, in RFC 3339 format
+//	JOB_TAGS        the command's tags, comma separated
+func StampEnv(prefix string) Option {
+	return func(s *command) {
+		s.setups = append(s.setups, func() error {
+			id := make([]byte, 8)
+			if _, err := rand.Read(id); err != nil {
+				return fmt.Errorf("StampEnv: %v", err)
+			}
+			s.Result.runID = fmt.Sprintf("%x", id)
+
+			if len(s.env) == 0 {
+				s.env = os.Environ()
+			}
+			s.env = append(
+				s.env,
+				prefix+"_RUN_ID="+s.Result.runID,
+				prefix+"_PARENT_PID="+strconv.Itoa(os.Getpid()),
+				prefix+"_START_TIME="+s.clock.Now().UTC().Format(time.RFC3339Nano),
+				prefix+"_TAGS="+strings.Join(s.Result.tags, ","),
+			)
+			return nil
+		})
+	}
+}
+
+// RunID returns the identifier given to the command by StampEnv,
+// or an empty string if none was
+func (r *Result) RunID() string {
+	return r.runID
+}
@@ -0,0 +1,47 @@
+package shell
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestStampEnv(t *testing.T) {
+	r := Run(
+		`echo "$JOB_RUN_ID|$JOB_PARENT_PID|$JOB_TAGS"; echo "$JOB_START_TIME"`,
+		StampEnv("JOB"), Tag("build", "nightly"),
+	)
+	if r.IsError() {
+		t.Fatalf("Unexpected error: %v", r.Err())
+	}
+
+	if r.RunID() == "" {
+		t.Fatal("Expected a run ID")
+	}
+
+	lines := r.Stdout().Lines()
+	want := r.RunID() + "|" + strconv.Itoa(os.Getpid()) + "|build,nightly"
+	if len(lines) != 2 || lines[0] != want {
+		t.Fatalf("Expected %q, got %q", want, lines)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, lines[1]); err != nil {
+		t.Errorf("Expected an RFC 3339 start time: %v", err)
+	}
+
+	if other := Run("true", StampEnv("JOB")); other.RunID() == r.RunID() {
+		t.Errorf("Expected each run to get its own ID")
+	}
+}
+
+func TestStampEnvClock(t *testing.T) {
+	r := Run(`echo "$JOB_START_TIME"`, StampEnv("JOB"), WithClock(&manualClock{}))
+	if r.IsError() {
+		t.Fatalf("Unexpected error: %v", r.Err())
+	}
+
+	want := time.Time{}.Format(time.RFC3339Nano)
+	if got := r.Stdout().Text(); got != want {
+		t.Errorf("Expected the start time %q from the clock, got %q", want, got)
+	}
+}
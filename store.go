@@ -0,0 +1,161 @@
+package shell
+
+import (
+	"sync"
+	"time"
+)
+
+// Store retains Results so that they can be queried later
+type Store interface {
+	// Add records a Result that is done
+	Add(r *Result)
+
+	// Query returns the recorded Results matching the filter,
+	// most recently started first
+	Query(f Filter) []*Result
+}
+
+// Filter selects Results from a Store. Zero-valued fields match everything.
+type Filter struct {
+	// Tag requires the Result to carry the tag
+	Tag string
+
+	// ExitCode, if not nil, requires the Result to have this exit code
+	ExitCode *int
+
+	// Failed requires the command to have errored, timed out,
+	// been canceled or to have exited with a non-zero code
+	Failed bool
+
+	// Since and Until bound when the command started
+	Since time.Time
+	Until time.Time
+
+	// Limit caps the number of Results returned
+	Limit int
+}
+
+// Record is an Option to add the command's Result to
+// the given Store once the command is done
+func Record(store Store) Option {
+	return func(s *command) {
+		s.cleanups = append(s.cleanups, func() {
+			store.Add(s.Result)
+		})
+	}
+}
+
+// ------------------------------------------------------------------
+
+// MemoryStore is a Store holding a bounded number of the
+// most recent Results in memory. It is safe for concurrent use.
+type MemoryStore struct {
+	mu       sync.Mutex
+	results  []*Result // ring buffer
+	next     int
+	size     int
+	capacity int
+}
+
+// NewMemoryStore creates a MemoryStore retaining
+// at most capacity Results, dropping the oldest first
+func NewMemoryStore(capacity int) *MemoryStore {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &MemoryStore{
+		results:  make([]*Result, capacity),
+		capacity: capacity,
+	}
+}
+
+// Add records the Result, evicting the oldest if the store is full
+func (m *MemoryStore) Add(r *Result) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.results[m.next] = r
+	m.next = (m.next + 1) % m.capacity
+	if m.size < m.capacity {
+		m.size++
+	}
+}
+
+// Query returns the Results matching the filter, most recently started first
+func (m *MemoryStore) Query(f Filter) []*Result {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	matches := []*Result{}
+	for i := 1; i <= m.size; i++ {
+		r := m.results[(m.next-i+m.capacity)%m.capacity]
+		if f.matches(r) {
+			matches = append(matches, r)
+		}
+	}
+
+	// results are held in the order they were done,
+	// which is not necessarily the order they started
+	sortByStart(matches)
+
+	if f.Limit > 0 && len(matches) > f.Limit {
+		matches = matches[:f.Limit]
+	}
+	return matches
+}
+
+// ------------------------------------------------------------------
+
+// matches indicates if the Result satisfies the filter
+func (f Filter) matches(r *Result) bool {
+	if f.Tag != "" && !hasTag(r, f.Tag) {
+		return false
+	}
+
+	if f.ExitCode != nil && r.ExitCode() != *f.ExitCode {
+		return false
+	}
+
+	if f.Failed && !failed(r) {
+		return false
+	}
+
+	started := startTime(r)
+	if !f.Since.IsZero() && started.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && started.After(f.Until) {
+		return false
+	}
+
+	return true
+}
+
+func hasTag(r *Result, tag string) bool {
+	for _, t := range r.tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// failed indicates if the command did not run to a successful completion
+func failed(r *Result) bool {
+	return r.IsError() || r.TimedOut() || r.Canceled() || r.Crashed() || r.ExitCode() != 0
+}
+
+func startTime(r *Result) time.Time {
+	return time.Unix(0, r.status().StartTs)
+}
+
+// sortByStart orders the results, most recently started first
+func sortByStart(results []*Result) {
+	// insertion sort: the input is nearly sorted already
+	for i := 1; i < len(results); i++ {
+		for j := i; j > 0 && startTime(results[j]).After(startTime(results[j-1])); j-- {
+			results[j], results[j-1] = results[j-1], results[j]
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package shell
+
+import "testing"
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore(3)
+
+	for _, c := range []string{"exit 1", "exit 0", "exit 2", "exit 3"} {
+		res := Run(c, Record(store), Tag("backup"))
+		<-res.Ready()
+	}
+	res := Run("exit 4", Record(store), Tag("other"))
+	<-res.Ready()
+
+	// capacity 3: only "exit 2", "exit 3" and "exit 4" remain
+	failures := store.Query(Filter{Tag: "backup", Failed: true})
+	if len(failures) != 2 {
+		t.Fatalf("Expected 2 failed backups, got %d", len(failures))
+	}
+
+	if failures[0].ExitCode() != 3 || failures[1].ExitCode() != 2 {
+		t.Errorf("Expected most recent first, got exit codes %d, %d",
+			failures[0].ExitCode(), failures[1].ExitCode())
+	}
+
+	code := 4
+	if got := store.Query(Filter{ExitCode: &code}); len(got) != 1 || got[0].Tags()[0] != "other" {
+		t.Errorf("Expected to find the command exiting with 4, got %v", got)
+	}
+
+	if got := store.Query(Filter{Limit: 1}); len(got) != 1 || got[0].ExitCode() != 4 {
+		t.Errorf("Expected the most recent command only, got %v", got)
+	}
+}
@@ -0,0 +1,97 @@
+package shell
+
+// streamSize is the number of lines each stream
+// channel buffers before the command blocks
+const streamSize = 1000
+
+// streams delivers output lines over channels as they are produced
+type streams struct {
+	stdout chan string
+	stderr chan string
+}
+
+// StdoutStream returns a channel delivering stdout lines as the
+// command produces them, closed once the command is done. It returns
+// nil unless the command was run with the Stream Option.
+func (r *Result) StdoutStream() <-chan string {
+	if r.streams == nil {
+		return nil
+	}
+	return r.streams.stdout
+}
+
+// StderrStream returns a channel delivering stderr lines as the
+// command produces them, closed once the command is done. It returns
+// nil unless the command was run with the Stream Option.
+func (r *Result) StderrStream() <-chan string {
+	if r.streams == nil {
+		return nil
+	}
+	return r.streams.stderr
+}
+
+// Stream is an Option to deliver output lines over the channels
+// returned by Result.StdoutStream and Result.StderrStream, as well
+// as capturing them as usual. Each channel buffers a limited number
+// of lines, after which the command blocks until lines are read, so
+// callers must drain both channels.
+func Stream() Option {
+	return func(s *command) {
+		s.Result.streams = &streams{
+			stdout: make(chan string, streamSize),
+			stderr: make(chan string, streamSize),
+		}
+	}
+}
+
+// addStreams wires the stream channels to the command's output
+func (sc *command) addStreams() {
+	st := sc.Result.streams
+	sc.addLineWriters(
+		newLineWriter(func(line string) { st.stdout <- line }),
+		newLineWriter(func(line string) { st.stderr <- line }),
+	)
+
+	// closing the line writers may flush a last line,
+	// so the channels must be closed only afterwards
+	sc.cleanups = append(sc.cleanups, func() {
+		close(st.stdout)
+		close(st.stderr)
+	})
+}
+
+// OnStdoutLine is an Option to call fn with each line of stdout
+// as it arrives. Calls are made from the goroutine copying the
+// command's output, so a slow fn holds the command up. Multiple
+// calls to this function will be taken into account.
+func OnStdoutLine(fn func(line string)) Option {
+	return func(s *command) {
+		s.onStdout = append(s.onStdout, fn)
+	}
+}
+
+// OnStderrLine is an Option to call fn with each line of stderr
+// as it arrives. Calls are made from the goroutine copying the
+// command's output, so a slow fn holds the command up. Multiple
+// calls to this function will be taken into account.
+func OnStderrLine(fn func(line string)) Option {
+	return func(s *command) {
+		s.onStderr = append(s.onStderr, fn)
+	}
+}
+
+// addLineCallbacks wires the line callbacks to the command's output
+func (sc *command) addLineCallbacks() {
+	call := func(fns []func(string)) func(string) {
+		return func(line string) {
+			for _, fn := range fns {
+				fn(line)
+			}
+		}
+	}
+
+	sc.addLineWriters(
+		newLineWriter(call(sc.onStdout)),
+		newLineWriter(call(sc.onStderr)),
+	)
+}
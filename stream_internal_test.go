@@ -0,0 +1,59 @@
+package shell
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestStreamOption(t *testing.T) {
+	res := Run("for i in 1 2 3; do echo out$i; echo err$i >&2; done; printf tail", Stream(), Bkgd())
+
+	var (
+		wg       sync.WaitGroup
+		out, err []string
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for line := range res.StdoutStream() {
+			out = append(out, line)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for line := range res.StderrStream() {
+			err = append(err, line)
+		}
+	}()
+	wg.Wait()
+
+	if got := strings.Join(out, ","); got != "out1,out2,out3,tail" {
+		t.Errorf("Unexpected stdout stream: %s", got)
+	}
+	if got := strings.Join(err, ","); got != "err1,err2,err3" {
+		t.Errorf("Unexpected stderr stream: %s", got)
+	}
+
+	<-res.Ready()
+	if got := res.Stdout().Lines(); len(got) != 4 {
+		t.Errorf("Expected output to still be captured, got %q", got)
+	}
+}
+
+func TestLineCallbackOptions(t *testing.T) {
+	var out, err []string
+	res := Run(
+		"echo 10%; echo warning >&2; echo 100%",
+		OnStdoutLine(func(line string) { out = append(out, line) }),
+		OnStderrLine(func(line string) { err = append(err, line) }),
+	)
+	<-res.Ready()
+
+	if got := strings.Join(out, ","); got != "10%,100%" {
+		t.Errorf("Unexpected stdout lines: %s", got)
+	}
+	if got := strings.Join(err, ","); got != "warning" {
+		t.Errorf("Unexpected stderr lines: %s", got)
+	}
+}
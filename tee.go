@@ -0,0 +1,73 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// TeeStdout is an Option to copy the command's stdout to the file
+// at path as it is written, while still capturing it in the Result.
+// The file is appended to if appendTo is true, else truncated, and
+// is created if need be with permissions perm, or 0644 if perm is 0.
+// Multiple calls to this function will be taken into account.
+func TeeStdout(path string, appendTo bool, perm os.FileMode) Option {
+	return func(s *command) {
+		s.tee("TeeStdout", path, appendTo, perm, &s.stdout)
+	}
+}
+
+// TeeStderr is an Option to copy the command's stderr to the file
+// at path as it is written, while still capturing it in the Result.
+// The file is appended to if appendTo is true, else truncated, and
+// is created if need be with permissions perm, or 0644 if perm is 0.
+// Multiple calls to this function will be taken into account.
+func TeeStderr(path string, appendTo bool, perm os.FileMode) Option {
+	return func(s *command) {
+		s.tee("TeeStderr", path, appendTo, perm, &s.stderr)
+	}
+}
+
+// StdoutWriter is an Option to copy the command's stdout to w as it
+// is written, while still capturing it in the Result. Writes are made
+// from the goroutine copying the command's output, so a slow w holds
+// the command up. Multiple calls to this function will be taken into
+// account.
+func StdoutWriter(w io.Writer) Option {
+	return func(s *command) {
+		s.stdout = append(s.stdout, w)
+	}
+}
+
+// StderrWriter is an Option to copy the command's stderr to w as it
+// is written, while still capturing it in the Result. Writes are made
+// from the goroutine copying the command's output, so a slow w holds
+// the command up. Multiple calls to this function will be taken into
+// account.
+func StderrWriter(w io.Writer) Option {
+	return func(s *command) {
+		s.stderr = append(s.stderr, w)
+	}
+}
+
+// tee opens the file just before launch, adding it to the
+// stream's destinations, and closes it once the command is done
+func (sc *command) tee(option, path string, appendTo bool, perm os.FileMode, dest *[]io.Writer) {
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendTo {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	if perm == 0 {
+		perm = 0644
+	}
+
+	sc.setups = append(sc.setups, func() error {
+		f, err := os.OpenFile(path, flag, perm)
+		if err != nil {
+			return fmt.Errorf("%s: %v", option, err)
+		}
+		*dest = append(*dest, f)
+		sc.cleanups = append(sc.cleanups, func() { f.Close() })
+		return nil
+	})
+}
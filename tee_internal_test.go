@@ -0,0 +1,64 @@
+package shell
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestTeeOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "out.log")
+	errLog := filepath.Join(dir, "err.log")
+	if err := ioutil.WriteFile(out, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(errLog, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := Run("echo hello; echo oops >&2", TeeStdout(out, false, 0), TeeStderr(errLog, true, 0))
+	if r.Stdout().Text() != "hello" || r.Stderr().Text() != "oops" {
+		t.Errorf("Expected the output to still be captured, got %v", r)
+	}
+
+	if data, _ := ioutil.ReadFile(out); string(data) != "hello\n" {
+		t.Errorf("Expected stdout to replace the file content, got %q", data)
+	}
+	if data, _ := ioutil.ReadFile(errLog); string(data) != "old\noops\n" {
+		t.Errorf("Expected stderr to be appended to the file, got %q", data)
+	}
+
+	if runtime.GOOS != "windows" {
+		created := filepath.Join(dir, "created.log")
+		Run("echo hi", TeeStdout(created, true, 0600))
+		if fi, err := os.Stat(created); err != nil || fi.Mode().Perm() != 0600 {
+			t.Errorf("Expected the file to be created with mode 0600, got %v, %v", fi, err)
+		}
+	}
+
+	r = Run("echo hi", TeeStdout(filepath.Join(dir, "missing", "out.log"), false, 0))
+	if r.Err() == nil || r.ExitCode() != -1 {
+		t.Errorf("Expected an unopenable file to fail the command, got %v", r)
+	}
+}
+
+func TestWriterOptions(t *testing.T) {
+	var stdout, stderr strings.Builder
+	r := Run("echo out; echo err >&2", StdoutWriter(&stdout), StderrWriter(&stderr))
+
+	if stdout.String() != "out\n" || stderr.String() != "err\n" {
+		t.Errorf("Unexpected copies %q and %q", stdout.String(), stderr.String())
+	}
+	if r.Stdout().Text() != "out" || r.Stderr().Text() != "err" {
+		t.Errorf("Expected the output to still be captured: %v", r)
+	}
+}
@@ -0,0 +1,91 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source identifies the output stream a Line came from
+type Source int
+
+const (
+	// Stdout is the command's standard output
+	Stdout Source = iota
+
+	// Stderr is the command's standard error
+	Stderr
+)
+
+// String returns the short label used for the source in transcripts
+func (s Source) String() string {
+	if s == Stderr {
+		return "err"
+	}
+	return "out"
+}
+
+// Line is a line of output along with its origin
+type Line struct {
+	Source Source
+	Time   time.Time
+	Text   string
+}
+
+// transcript records the lines of both output streams in arrival order
+type transcript struct {
+	mu    sync.Mutex
+	lines []Line
+	clock Clock
+}
+
+// writer returns a lineWriter adding lines from the given source
+func (t *transcript) writer(source Source) *lineWriter {
+	return newLineWriter(func(line string) {
+		t.mu.Lock()
+		t.lines = append(t.lines, Line{source, t.clock.Now(), line})
+		t.mu.Unlock()
+	})
+}
+
+func (t *transcript) snapshot() []Line {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Line{}, t.lines...)
+}
+
+// ------------------------------------------------------------------
+
+// Combined returns the lines of both stdout and stderr, in the order
+// they arrived, each tagged with its source. It is only available for
+// commands run with the Interleave Option, and returns nil otherwise.
+func (r *Result) Combined() []Line {
+	if r.transcript == nil {
+		return nil
+	}
+	return r.transcript.snapshot()
+}
+
+// Transcript returns the output of the command, stdout and stderr
+// interleaved in the order the lines arrived, each line labeled with
+// its stream and timestamp. It is only available for commands run with
+// the Interleave Option, and returns an empty string otherwise.
+func (r *Result) Transcript() string {
+	var b strings.Builder
+	for _, l := range r.Combined() {
+		fmt.Fprintf(&b, "[%s] %s %s\n", l.Source, l.Time.Format("15:04:05.000"), l.Text)
+	}
+	return b.String()
+}
+
+// Interleave is an Option to additionally record both output
+// streams together, in the order lines arrive, as used by
+// Result.Combined and Result.Transcript. Output ordering between
+// the streams is as observed by this process, and so only
+// approximately that of the command itself.
+func Interleave() Option {
+	return func(s *command) {
+		s.Result.transcript = &transcript{}
+	}
+}
@@ -0,0 +1,31 @@
+package shell
+
+import "errors"
+
+// KillTree immediately sends SIGKILL to the command's process group
+// and to every descendant of the command that can be found, including
+// those that have moved to a group of their own. It returns an error
+// once the command is done, as its PID may since have been reused.
+func (r *Result) KillTree() error {
+	pid := r.PID()
+	if pid <= 0 {
+		return errors.New("KillTree: command has no process")
+	}
+	if r.IsReady() {
+		return errors.New("KillTree: command is done")
+	}
+	return killTree(pid, descendants(pid))
+}
+
+// ProcessGroup is an Option to kill the command's whole process tree
+// with SIGKILL when the command is timed out or canceled, so that no
+// children are left behind. Any KillGrace period is honoured before
+// the tree is killed, and descendants are found once it has elapsed,
+// so that no process reusing the PID of one that exited is killed.
+// Without a grace period they are found before the command is first
+// signalled, as they are no longer traceable once orphaned.
+func ProcessGroup() Option {
+	return func(s *command) {
+		s.group = true
+	}
+}
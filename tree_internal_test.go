@@ -0,0 +1,73 @@
+package shell
+
+import (
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestKillTree(t *testing.T) {
+	result := Run("sleep 30 & sleep 30; wait", Bkgd())
+	for result.PID() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if err := result.KillTree(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-result.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the process tree to be killed")
+	}
+
+	if err := Run("true", UseProfile("no-such-profile")).KillTree(); err == nil {
+		t.Error("Expected an error for a command that never started")
+	}
+}
+
+func TestProcessGroupOption(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("escaped descendants are only found on Linux")
+	}
+	if _, err := exec.LookPath("setsid"); err != nil {
+		t.Skip("setsid is not available")
+	}
+
+	// the child leaves the process group, but keeps stdout open
+	start := time.Now()
+	result := Run("setsid sleep 30 & wait", Timeout(200*time.Millisecond), ProcessGroup())
+	<-result.Ready()
+
+	if !result.TimedOut() {
+		t.Error("Expected process to be marked timed out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the escaped child to be killed, took %v", elapsed)
+	}
+}
+
+func TestProcessGroupKillGrace(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("escaped descendants are only found on Linux")
+	}
+	if _, err := exec.LookPath("setsid"); err != nil {
+		t.Skip("setsid is not available")
+	}
+
+	// the escaped child is found once the grace period is over
+	start := time.Now()
+	result := Run(`trap "" TERM; setsid sleep 30 & wait`,
+		Timeout(200*time.Millisecond), KillGrace(200*time.Millisecond), ProcessGroup())
+	<-result.Ready()
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the escaped child to be killed, took %v", elapsed)
+	}
+	if err := result.KillTree(); err == nil {
+		t.Error("Expected an error for a command that is done")
+	}
+}
@@ -0,0 +1,25 @@
+package shell
+
+// descendants returns the PIDs of all processes descended from pid,
+// as found by walking the parent links in /proc
+func descendants(pid int) []int {
+	if pid <= 0 {
+		return nil
+	}
+
+	procs, _ := readProcesses()
+	children := map[int][]int{}
+	for _, p := range procs {
+		children[p.PPID] = append(children[p.PPID], p.PID)
+	}
+
+	var found []int
+	queue := children[pid]
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		found = append(found, p)
+		queue = append(queue, children[p]...)
+	}
+	return found
+}
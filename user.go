@@ -0,0 +1,72 @@
+package shell
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+)
+
+// credential identifies the user and groups a command runs as
+type credential struct {
+	uid    uint32
+	gid    uint32
+	groups []uint32
+}
+
+// User is an Option to run the command as the named user, with that
+// user's primary and supplementary groups. The current process must
+// be privileged to do so. The environment, including HOME, is left
+// unchanged. It is not supported on Windows.
+func User(name string) Option {
+	return func(s *command) {
+		u, err := user.Lookup(name)
+		if err != nil {
+			s.err = fmt.Errorf("User: %v", err)
+			return
+		}
+
+		cred, err := userCredential(u)
+		if err != nil {
+			s.err = fmt.Errorf("User: %v", err)
+			return
+		}
+		s.runAs("User", cred)
+	}
+}
+
+// Credential is an Option to run the command with the given user
+// and group IDs, and no supplementary groups. The current process
+// must be privileged to do so. It is not supported on Windows.
+func Credential(uid, gid uint32) Option {
+	return func(s *command) {
+		s.runAs("Credential", &credential{uid: uid, gid: gid})
+	}
+}
+
+// userCredential returns the IDs of the user and of its groups
+func userCredential(u *user.User) (*credential, error) {
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uid %q for %s", u.Uid, u.Username)
+	}
+
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gid %q for %s", u.Gid, u.Username)
+	}
+
+	cred := &credential{uid: uint32(uid), gid: uint32(gid)}
+	groups, err := u.GroupIds()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, g := range groups {
+		id, err := strconv.ParseUint(g, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid group id %q for %s", g, u.Username)
+		}
+		cred.groups = append(cred.groups, uint32(id))
+	}
+	return cred, nil
+}
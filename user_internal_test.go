@@ -0,0 +1,37 @@
+package shell
+
+import (
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestCredentialOption(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() != 0 {
+		t.Skip("changing user requires root")
+	}
+
+	res := Run("id -u; id -g; id -G; cat \"$TOKEN\"", Credential(65534, 65534), Secret("TOKEN", "s3cret"))
+	if got := res.Stdout().Lines(); len(got) != 4 || got[0] != "65534" || got[1] != "65534" || got[2] != "65534" || got[3] != "s3cret" {
+		t.Errorf("Unexpected identity: %q %v", got, res.Stderr().Lines())
+	}
+}
+
+func TestUserOption(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() != 0 {
+		t.Skip("changing user requires root")
+	}
+
+	res := Run("id -un", User("nobody"))
+	if res.IsError() {
+		t.Skipf("no nobody user: %v", res.Err())
+	}
+	if got := res.Stdout().Text(); got != "nobody" {
+		t.Errorf("Expected to run as nobody, got %q", got)
+	}
+
+	res = Run("true", User("no-such-user-here"))
+	if !res.IsError() {
+		t.Error("Expected an error for an unknown user")
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+// +build !windows
+
+package shell
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// runAs sets the credentials the command's process is started with
+func (sc *command) runAs(option string, cred *credential) {
+	sc.user = cred
+	sc.beforeExec = append(sc.beforeExec, func(c *exec.Cmd) {
+		if c.SysProcAttr == nil {
+			c.SysProcAttr = &syscall.SysProcAttr{}
+		}
+
+		c.SysProcAttr.Credential = &syscall.Credential{
+			Uid:    cred.uid,
+			Gid:    cred.gid,
+			Groups: cred.groups,
+		}
+	})
+}
@@ -0,0 +1,9 @@
+package shell
+
+import "fmt"
+
+// runAs is not supported: Windows has no equivalent
+// of starting a process with another user's IDs
+func (sc *command) runAs(option string, cred *credential) {
+	sc.err = fmt.Errorf("%s: %w", option, ErrUnsupported)
+}